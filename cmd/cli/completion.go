@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runCompletion prints a completion script for bash, zsh or fish, built from
+// the commands and their flags.
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: codecritique completion bash|zsh|fish")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		return fmt.Errorf("unsupported shell: %s", args[0])
+	}
+	return nil
+}
+
+func commandNames() string {
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return strings.Join(names, " ")
+}
+
+// flagNames lists the flags of a command as --name.
+func flagNames(cmd command) string {
+	if cmd.flags == nil {
+		return ""
+	}
+
+	var names []string
+	cmd.flags().VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	return strings.Join(names, " ")
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString("_codecritique() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", commandNames())
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "    %s)\n", cmd.name)
+		if len(cmd.subcommands) > 0 {
+			b.WriteString("        if [ \"$COMP_CWORD\" -eq 2 ]; then\n")
+			fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(cmd.subcommands, " "))
+			b.WriteString("            return\n")
+			b.WriteString("        fi\n")
+		}
+		if flags := flagNames(cmd); flags != "" {
+			b.WriteString("        if [[ \"$cur\" == -* ]]; then\n")
+			fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", flags)
+			b.WriteString("        fi\n")
+		}
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o default -F _codecritique codecritique\n")
+	return b.String()
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString("#compdef codecritique\n\n")
+	b.WriteString("_codecritique() {\n")
+	b.WriteString("    if (( CURRENT == 2 )); then\n")
+	fmt.Fprintf(&b, "        compadd -- %s\n", commandNames())
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case $words[2] in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "    %s)\n", cmd.name)
+		if len(cmd.subcommands) > 0 {
+			b.WriteString("        if (( CURRENT == 3 )); then\n")
+			fmt.Fprintf(&b, "            compadd -- %s\n", strings.Join(cmd.subcommands, " "))
+			b.WriteString("            return\n")
+			b.WriteString("        fi\n")
+		}
+		if flags := flagNames(cmd); flags != "" {
+			b.WriteString("        if [[ $PREFIX == -* ]]; then\n")
+			fmt.Fprintf(&b, "            compadd -- %s\n", flags)
+			b.WriteString("        else\n")
+			b.WriteString("            _files\n")
+			b.WriteString("        fi\n")
+		}
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	b.WriteString("compdef _codecritique codecritique\n")
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "complete -c codecritique -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	for _, cmd := range commands {
+		condition := fishQuote("__fish_seen_subcommand_from " + cmd.name)
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(&b, "complete -c codecritique -f -n %s -a %s\n", condition, sub)
+		}
+		if cmd.flags == nil {
+			continue
+		}
+		cmd.flags().VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&b, "complete -c codecritique -n %s -l %s -d %s\n", condition, f.Name, fishQuote(f.Usage))
+		})
+	}
+	return b.String()
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
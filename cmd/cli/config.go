@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+type configFlags struct {
+	*flag.FlagSet
+	options
+	path  string
+	force bool
+}
+
+func newConfigFlags(subcommand string) *configFlags {
+	f := &configFlags{FlagSet: flag.NewFlagSet("codecritique config "+subcommand, flag.ExitOnError)}
+	f.register(f.FlagSet)
+	f.StringVar(&f.path, "path", ".codecritique.toml", "file that init writes, or - for stdout")
+	f.BoolVar(&f.force, "force", false, "let init overwrite an existing file")
+	return f
+}
+
+// runConfig runs "config init", which writes a starter configuration file,
+// "config show", which prints the effective configuration with the source
+// of every key and the secrets redacted, and "config validate", which
+// reports every problem of the configuration.
+func runConfig(args []string) error {
+	if len(args) == 0 || (args[0] != "init" && args[0] != "show" && args[0] != "validate") {
+		fmt.Fprintln(os.Stderr, "Usage: codecritique config init|validate|show [flags]")
+		return errUsage
+	}
+
+	flags := newConfigFlags(args[0])
+	_ = flags.Parse(args[1:])
+
+	if args[0] == "init" {
+		return initConfig(flags.path, flags.force)
+	}
+
+	cfg, sources, err := flags.load()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "show":
+		if err := config.Show(os.Stdout, cfg, sources); err != nil {
+			return fmt.Errorf("could not show configuration: %w", err)
+		}
+	case "validate":
+		if err := config.Validate(cfg, sources); err != nil {
+			return err
+		}
+		fmt.Println("Configuration is valid")
+	}
+	return nil
+}
+
+func initConfig(path string, force bool) error {
+	if path == "-" {
+		return config.WriteTemplate(os.Stdout, false)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	// The file is meant to hold tokens, so it is only readable by its owner.
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err != nil {
+		return fmt.Errorf("could not create configuration file: %w", err)
+	}
+	defer f.Close()
+
+	workingTree := filepath.Base(path) == ".codecritique.toml" || filepath.Clean(path) == filepath.Join("settings", "settings.toml")
+	if err := config.WriteTemplate(f, workingTree); err != nil {
+		return fmt.Errorf("could not write configuration file: %w", err)
+	}
+
+	fmt.Printf("Wrote configuration to %s\n", path)
+	return nil
+}
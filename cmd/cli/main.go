@@ -1,53 +1,118 @@
 package main
 
 import (
-	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
-	"strings"
-
-	"github.com/holistic-engineering/codecritique/config"
-	"github.com/holistic-engineering/codecritique/internal/critique"
-	"github.com/holistic-engineering/codecritique/internal/infra/ai"
-	"github.com/holistic-engineering/codecritique/internal/infra/git"
-	"github.com/holistic-engineering/codecritique/internal/infra/printer"
 )
 
-func main() {
-	if len(os.Args) < 3 {
-		log.Fatal("Usage: codecritique <owner/repo> <pr_number>")
-	}
+// version is set at build time with -ldflags "-X main.version=<version>".
+var version = "dev"
 
-	cfg, err := config.LoadConfig("settings/settings.toml")
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %s", err)
-	}
+// errUsage is returned by commands that printed their usage after being
+// called with invalid arguments.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name        string
+	summary     string
+	subcommands []string
+	// flags returns the flags of the command for completion, or nil.
+	flags func() *flag.FlagSet
+	run   func(args []string) error
+}
 
-	repoPath, prNumber := os.Args[1], os.Args[2]
-	parts := strings.Split(repoPath, "/")
-	if len(parts) != 2 {
-		log.Fatal("Invalid repository path. Use the format: owner/repo")
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name:    "review",
+			summary: "Review a pull request, local changes or a patch (default)",
+			flags:   func() *flag.FlagSet { return newReviewFlags().FlagSet },
+			run:     runReview,
+		},
+		{
+			name:        "config",
+			summary:     "Create, validate or show the configuration",
+			subcommands: []string{"init", "validate", "show"},
+			flags:       func() *flag.FlagSet { return newConfigFlags("").FlagSet },
+			run:         runConfig,
+		},
+		{
+			name:    "serve",
+			summary: "Review pull requests over HTTP",
+			flags:   func() *flag.FlagSet { return newServeFlags().FlagSet },
+			run:     runServe,
+		},
+		{
+			name:    "version",
+			summary: "Print the version",
+			run:     runVersion,
+		},
+		{
+			name:        "completion",
+			summary:     "Generate a shell completion script",
+			subcommands: []string{"bash", "zsh", "fish"},
+			run:         runCompletion,
+		},
+		{
+			name:    "help",
+			summary: "Show this help",
+			run: func([]string) error {
+				printUsage()
+				return nil
+			},
+		},
 	}
+}
 
-	owner, repo := parts[0], parts[1]
+func main() {
+	args := os.Args[1:]
 
-	git, err := git.New(&cfg.Git)
-	if err != nil {
-		log.Fatalf("could not initialize git client: %s", err)
+	// Without a command, the arguments are those of review.
+	// Top-level help lists the commands along with the flags of review.
+	run := runReview
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		printUsage()
+		fmt.Fprintln(os.Stderr)
+		newReviewFlags().Usage()
+		return
+	}
+	if len(args) > 0 {
+		for _, cmd := range commands {
+			if cmd.name == args[0] {
+				run, args = cmd.run, args[1:]
+				break
+			}
+		}
 	}
 
-	ai, err := ai.New(&cfg.AI)
-	if err != nil {
-		log.Fatalf("could not initilize ai client: %s", err)
+	if err := run(args); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+		os.Exit(1)
 	}
+}
 
-	printer, err := printer.New(&cfg.Printer)
-	if err != nil {
-		log.Fatalf("could not initilize printer: %s", err)
+func isHelpFlag(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help":
+		return true
+	default:
+		return false
 	}
+}
 
-	critique := critique.New(git, ai, printer)
-	if err := critique.Criticize(context.Background(), owner, repo, prNumber); err != nil {
-		log.Fatalf("could not criticize pull request: %s", err)
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: codecritique <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "codecritique <command> -h" for the flags of a command.`)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+// modelKeys names the model key of every AI provider, which --model sets.
+var modelKeys = map[string]string{
+	"Anthropic":        "ai.anthropic_model",
+	"Groq":             "ai.groq_model",
+	"Ollama":           "ai.ollama_model",
+	"OpenAI":           "ai.openai_model",
+	"OpenAICompatible": "ai.compatible_model",
+}
+
+// options are the flags shared by the commands that load the
+// configuration. They override the configuration files and the
+// environment.
+type options struct {
+	configPath  string
+	printerKind string
+	aiProvider  string
+	model       string
+	logLevel    string
+}
+
+func (o *options) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.configPath, "config", "", "read this configuration file on top of the discovered ones")
+	flags.StringVar(&o.printerKind, "printer", "", "output format: json, html or markdown")
+	flags.StringVar(&o.aiProvider, "provider", "", "AI provider: Anthropic, Groq, Ollama, OpenAI or OpenAICompatible")
+	flags.StringVar(&o.model, "model", "", "model of the selected AI provider")
+	flags.StringVar(&o.logLevel, "log-level", "info", "log level: debug, info, warn or error")
+}
+
+// load sets up logging and loads the configuration with the flags applied
+// on top. The configuration is not validated yet, since the arguments of
+// a review may still change it.
+func (o *options) load() (*config.Config, *config.Sources, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(o.logLevel)); err != nil {
+		return nil, nil, fmt.Errorf("invalid log level %q: %w", o.logLevel, err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	cfg, sources, err := config.Load(o.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	set := func(name, key, value string) error {
+		if value == "" {
+			return nil
+		}
+		if err := cfg.Set(key, value); err != nil {
+			return err
+		}
+		sources.Set(key, "flag --"+name)
+		return nil
+	}
+
+	if err := set("printer", "printer.kind", o.printerKind); err != nil {
+		return nil, nil, err
+	}
+	if err := set("provider", "ai.provider", o.aiProvider); err != nil {
+		return nil, nil, err
+	}
+	if o.model != "" {
+		key, ok := modelKeys[cfg.AI.Provider]
+		if !ok {
+			return nil, nil, fmt.Errorf("cannot set --model for unknown AI provider %q", cfg.AI.Provider)
+		}
+		if err := set("model", key, o.model); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	slog.Debug("loaded configuration", "git_provider", cfg.Git.Provider, "ai_provider", cfg.AI.Provider, "printer", cfg.Printer.Kind)
+	return cfg, sources, nil
+}
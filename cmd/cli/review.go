@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique"
+	"github.com/holistic-engineering/codecritique/internal/infra/ai"
+	"github.com/holistic-engineering/codecritique/internal/infra/git"
+	"github.com/holistic-engineering/codecritique/internal/infra/printer"
+)
+
+type reviewFlags struct {
+	*flag.FlagSet
+	options
+	output   string
+	base     string
+	staged   bool
+	unstaged bool
+	patch    string
+}
+
+func newReviewFlags() *reviewFlags {
+	f := &reviewFlags{FlagSet: flag.NewFlagSet("codecritique review", flag.ExitOnError)}
+	f.register(f.FlagSet)
+	f.StringVar(&f.output, "output", "", "write the review to this file instead of stdout")
+	f.StringVar(&f.base, "base", "", "review the current branch of the local repository against this ref")
+	f.BoolVar(&f.staged, "staged", false, "review the staged changes of the local repository")
+	f.BoolVar(&f.unstaged, "unstaged", false, "review the unstaged changes of the local repository")
+	f.StringVar(&f.patch, "patch", "", "review a unified diff or format-patch file, or stdin when set to -")
+	f.Usage = func() {
+		fmt.Fprintln(f.Output(), "Usage: codecritique [review] [flags] <repository> <pr_number>")
+		fmt.Fprintln(f.Output(), "       codecritique [review] [flags] <pull_request_url>")
+		fmt.Fprintln(f.Output(), "       codecritique [review] [flags] --base <ref> | --staged | --unstaged")
+		fmt.Fprintln(f.Output(), "       codecritique [review] [flags] --patch <file|->")
+		f.PrintDefaults()
+	}
+	return f
+}
+
+func runReview(args []string) error {
+	flags := newReviewFlags()
+	_ = flags.Parse(args)
+
+	localMode, err := parseLocalMode(flags.base, flags.staged, flags.unstaged)
+	if err != nil {
+		return err
+	}
+	if localMode != "" && flags.patch != "" {
+		return fmt.Errorf("--patch cannot be combined with --base, --staged or --unstaged")
+	}
+
+	cfg, sources, err := flags.load()
+	if err != nil {
+		return err
+	}
+
+	// Flags after the first argument are not parsed, so extra arguments are
+	// rejected rather than silently ignored.
+	maxArgs := 2
+	switch {
+	case localMode != "" || flags.patch != "":
+		maxArgs = 0
+	case flags.NArg() > 0 && git.IsURL(flags.Arg(0)):
+		maxArgs = 1
+	}
+	if flags.NArg() > maxArgs {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %s; flags must come before the arguments\n", strings.Join(flags.Args()[maxArgs:], " "))
+		flags.Usage()
+		return errUsage
+	}
+
+	var repository, prNumber string
+	if localMode == "" && flags.patch == "" {
+		switch {
+		case flags.NArg() == 1 && git.IsURL(flags.Arg(0)):
+			target, err := git.ParseURL(flags.Arg(0))
+			if err != nil {
+				return err
+			}
+			if applyTarget(&cfg.Git, target) {
+				for _, key := range []string{"git.provider", "git.base_url", "git.upload_url", "git.username", "git.token"} {
+					sources.Set(key, "pull request URL")
+				}
+				if _, name := config.GitTokenFromEnv(cfg.Git.Provider); name != "" && cfg.Git.Token != "" {
+					sources.Set("git.token", "env "+name)
+				}
+				if cfg.Git.Token == "" {
+					slog.Warn("reviewing without a token, since none is configured for the host of the pull request", "host", target.Host())
+				}
+			}
+			repository, prNumber = target.Repository, target.Number
+		case flags.NArg() == 2:
+			// The repository is passed on as a whole; how many segments it
+			// has depends on the provider, and GitLab also takes numeric
+			// project IDs.
+			repository, prNumber = strings.Trim(flags.Arg(0), "/"), flags.Arg(1)
+			if repository == "" {
+				return fmt.Errorf("invalid repository path, use the format: owner/repo")
+			}
+		default:
+			flags.Usage()
+			return errUsage
+		}
+	}
+
+	if err := config.Validate(cfg, sources); err != nil {
+		return err
+	}
+
+	ai, err := ai.New(&cfg.AI)
+	if err != nil {
+		return fmt.Errorf("could not initilize ai client: %w", err)
+	}
+
+	printer, err := printer.New(&cfg.Printer)
+	if err != nil {
+		return fmt.Errorf("could not initilize printer: %w", err)
+	}
+	// The output file is only written once the review succeeded, so a
+	// failed run leaves an earlier review in place.
+	var output bytes.Buffer
+	if flags.output != "" {
+		printer.WithOutput(&output)
+	}
+
+	var critic *critique.Critique
+	switch {
+	case flags.patch != "":
+		source, err := openPatch(flags.patch)
+		if err != nil {
+			return fmt.Errorf("could not read patch: %w", err)
+		}
+		critic = critique.New(source, ai, printer)
+	case localMode != "":
+		local, err := git.NewLocal(".", flags.base, localMode)
+		if err != nil {
+			return fmt.Errorf("could not initialize local git client: %w", err)
+		}
+		critic = critique.New(local, ai, printer)
+	default:
+		critic, err = newRemoteCritique(&cfg.Git, ai, printer)
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Debug("reviewing", "repository", repository, "number", prNumber, "patch", flags.patch, "local_mode", localMode)
+	if err := critic.Criticize(context.Background(), repository, prNumber); err != nil {
+		return fmt.Errorf("could not criticize pull request: %w", err)
+	}
+
+	if flags.output != "" {
+		if err := writeFileAtomic(flags.output, output.Bytes()); err != nil {
+			return fmt.Errorf("could not write output file: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so that readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// newRemoteCritique builds a Critique for a pull request of a Git provider
+// that also publishes the review when configured.
+func newRemoteCritique(cfg *config.GitConfig, reviewer *ai.Client, printer *printer.Printer) (*critique.Critique, error) {
+	client, err := git.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize git client: %w", err)
+	}
+
+	critic := critique.New(client, reviewer, printer)
+	if cfg.Publish {
+		critic = critic.WithPublisher(client)
+	}
+	return critic, nil
+}
+
+// applyTarget points the Git configuration at the provider and host of a
+// pull request URL and reports whether it changed anything. A configured
+// base URL is kept when it already belongs to the host of the URL, since it
+// may differ from what the web URL suggests. The configured credentials are
+// never sent to another host: they are dropped, and for the public services
+// the token is looked up again in the conventional variables of the
+// provider.
+func applyTarget(cfg *config.GitConfig, target *git.Target) bool {
+	provider := git.Provider(cfg.Provider)
+	if provider == target.Provider && git.Host(provider, cfg.BaseURL) == target.Host() {
+		return false
+	}
+
+	cfg.Provider = string(target.Provider)
+	cfg.BaseURL = target.BaseURL
+	cfg.UploadURL = target.UploadURL
+	cfg.Username = ""
+	cfg.Token = ""
+	if target.BaseURL == "" {
+		cfg.Token, _ = config.GitTokenFromEnv(cfg.Provider)
+	}
+	return true
+}
+
+// openPatch reads a patch from the named file, or from stdin for "-".
+func openPatch(name string) (*git.Patch, error) {
+	if name == "-" {
+		return git.NewPatch("stdin", os.Stdin)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return git.NewPatch(filepath.Base(name), f)
+}
+
+// parseLocalMode returns the local mode selected by the flags, or an empty
+// mode when a hosted pull request is to be reviewed.
+func parseLocalMode(base string, staged, unstaged bool) (git.LocalMode, error) {
+	var modes []git.LocalMode
+	if base != "" {
+		modes = append(modes, git.LocalBase)
+	}
+	if staged {
+		modes = append(modes, git.LocalStaged)
+	}
+	if unstaged {
+		modes = append(modes, git.LocalUnstaged)
+	}
+
+	switch len(modes) {
+	case 0:
+		return "", nil
+	case 1:
+		return modes[0], nil
+	default:
+		return "", fmt.Errorf("--base, --staged and --unstaged are mutually exclusive")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/infra/git"
+)
+
+func TestApplyTarget(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "github-env")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITLAB_TOKEN", "gitlab-env")
+
+	enterprise := config.GitConfig{Provider: "GitHub", BaseURL: "https://github.example.com/api/v3/", Token: "configured"}
+	tests := []struct {
+		name        string
+		cfg         config.GitConfig
+		url         string
+		wantChanged bool
+		wantBaseURL string
+		wantToken   string
+	}{
+		{
+			name:      "configured host",
+			cfg:       config.GitConfig{Provider: "GitHub", Token: "configured"},
+			url:       "https://github.com/o/r/pull/1",
+			wantToken: "configured",
+		},
+		{
+			name:        "configured enterprise host",
+			cfg:         enterprise,
+			url:         "https://github.example.com/o/r/pull/1",
+			wantBaseURL: "https://github.example.com/api/v3/",
+			wantToken:   "configured",
+		},
+		{
+			name:        "public host of another provider",
+			cfg:         config.GitConfig{Provider: "GitHub", Token: "configured", Username: "jane"},
+			url:         "https://gitlab.com/g/p/-/merge_requests/2",
+			wantChanged: true,
+			wantToken:   "gitlab-env",
+		},
+		{
+			name:        "public host instead of the configured one",
+			cfg:         enterprise,
+			url:         "https://github.com/o/r/pull/1",
+			wantChanged: true,
+			wantToken:   "github-env",
+		},
+		{
+			name:        "host that no configuration names",
+			cfg:         config.GitConfig{Provider: "GitHub", Token: "configured"},
+			url:         "https://github.attacker.example/o/r/pull/1",
+			wantChanged: true,
+			wantBaseURL: "https://github.attacker.example/api/v3/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := git.ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+
+			cfg := tt.cfg
+			if changed := applyTarget(&cfg, target); changed != tt.wantChanged {
+				t.Errorf("applyTarget() = %v, want %v", changed, tt.wantChanged)
+			}
+			if cfg.Provider != string(target.Provider) {
+				t.Errorf("Provider = %q, want %q", cfg.Provider, target.Provider)
+			}
+			if cfg.BaseURL != tt.wantBaseURL {
+				t.Errorf("BaseURL = %q, want %q", cfg.BaseURL, tt.wantBaseURL)
+			}
+			if cfg.Token != tt.wantToken {
+				t.Errorf("Token = %q, want %q", cfg.Token, tt.wantToken)
+			}
+			if tt.wantChanged && cfg.Username != "" {
+				t.Errorf("Username = %q, want it dropped", cfg.Username)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/infra/ai"
+	"github.com/holistic-engineering/codecritique/internal/infra/git"
+	"github.com/holistic-engineering/codecritique/internal/infra/printer"
+)
+
+const maxRequestBytes = 1 << 20
+
+// serveTokenEnv holds the shared secret that clients of the server must send
+// as a bearer token.
+const serveTokenEnv = "CODECRITIQUE_SERVE_TOKEN"
+
+var contentTypes = map[printer.Kind]string{
+	printer.KindJSON:     "application/json",
+	printer.KindHTML:     "text/html; charset=utf-8",
+	printer.KindMarkdown: "text/markdown; charset=utf-8",
+}
+
+type serveFlags struct {
+	*flag.FlagSet
+	options
+	addr string
+}
+
+func newServeFlags() *serveFlags {
+	f := &serveFlags{FlagSet: flag.NewFlagSet("codecritique serve", flag.ExitOnError)}
+	f.register(f.FlagSet)
+	f.StringVar(&f.addr, "addr", "127.0.0.1:8080", "address to listen on")
+	return f
+}
+
+// runServe serves reviews over HTTP. POST /review takes a JSON body with
+// either the "url" of a pull request or its "repository" and "number", and
+// responds with the review in the configured printer format. GET /healthz
+// reports that the server is up. Reviews are only served to clients that send
+// the secret in CODECRITIQUE_SERVE_TOKEN as a bearer token, and only for the
+// configured Git provider and host.
+func runServe(args []string) error {
+	flags := newServeFlags()
+	_ = flags.Parse(args)
+
+	token := os.Getenv(serveTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s must be set to the secret that clients authenticate with", serveTokenEnv)
+	}
+
+	cfg, sources, err := flags.load()
+	if err != nil {
+		return err
+	}
+	if err := config.Validate(cfg, sources); err != nil {
+		return err
+	}
+
+	reviewer, err := ai.New(&cfg.AI)
+	if err != nil {
+		return fmt.Errorf("could not initilize ai client: %w", err)
+	}
+
+	s := &server{cfg: cfg, reviewer: reviewer, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("POST /review", s.authenticate(s.review))
+
+	srv := &http.Server{
+		Addr:              flags.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("could not shut down server", "error", err)
+		}
+	}()
+
+	slog.Info("listening", "addr", flags.addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("could not serve: %w", err)
+	}
+	return nil
+}
+
+type server struct {
+	cfg      *config.Config
+	reviewer *ai.Client
+	token    string
+}
+
+// authenticate rejects requests that do not carry the shared secret as a
+// bearer token.
+func (s *server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid bearer token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+type reviewRequest struct {
+	URL        string `json:"url"`
+	Repository string `json:"repository"`
+	Number     string `json:"number"`
+}
+
+func (s *server) review(w http.ResponseWriter, r *http.Request) {
+	var req reviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	// The configured token is only ever sent to the configured host, so
+	// URLs must point at a pull request there.
+	cfg := s.cfg
+	repository, number := strings.Trim(req.Repository, "/"), req.Number
+	if req.URL != "" {
+		target, err := git.ParseURL(req.URL)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		provider := git.Provider(cfg.Git.Provider)
+		if target.Provider != provider || target.Host() != git.Host(provider, cfg.Git.BaseURL) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pull request URL is not on the configured %s host", cfg.Git.Provider))
+			return
+		}
+		repository, number = target.Repository, target.Number
+	}
+	if repository == "" || number == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(`either "url" or "repository" and "number" are required`))
+		return
+	}
+
+	var buf bytes.Buffer
+	printer, err := printer.New(&cfg.Printer)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("could not initilize printer: %w", err))
+		return
+	}
+	printer.WithOutput(&buf)
+
+	critic, err := newRemoteCritique(&cfg.Git, s.reviewer, printer)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	slog.Info("reviewing", "provider", cfg.Git.Provider, "repository", repository, "number", number)
+	if err := critic.Criticize(r.Context(), repository, number); err != nil {
+		slog.Error("could not criticize pull request", "repository", repository, "number", number, "error", err)
+		writeError(w, http.StatusBadGateway, fmt.Errorf("could not criticize pull request: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[printer.Kind()])
+	_, _ = buf.WriteTo(w)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
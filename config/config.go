@@ -1,9 +1,5 @@
 package config
 
-import (
-	"github.com/pelletier/go-toml"
-)
-
 type Config struct {
 	Git     GitConfig     `toml:"git"`
 	AI      AIConfig      `toml:"ai"`
@@ -12,30 +8,58 @@ type Config struct {
 
 type GitConfig struct {
 	Provider string `toml:"provider"`
-	Token    string `toml:"token"`
+	Token    string `toml:"token" secret:"true"`
+	Username string `toml:"username"`
+	Publish  bool   `toml:"publish"`
+
+	BaseURL            string `toml:"base_url" trusted:"true"`
+	UploadURL          string `toml:"upload_url" trusted:"true"`
+	CAFile             string `toml:"ca_file" trusted:"true"`
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify" trusted:"true"`
 }
 
 type AIConfig struct {
-	Provider    string `toml:"provider"`
-	OllamaURL   string `toml:"ollama_url"`
-	OllamaModel string `toml:"ollama_model"`
-	GroqAPIKey  string `toml:"groq_api_key"`
-	GroqModel   string `toml:"groq_model"`
+	Provider          string   `toml:"provider"`
+	FallbackProviders []string `toml:"fallback_providers"`
+	MaxPromptTokens   int      `toml:"max_prompt_tokens"`
+
+	TimeoutSeconds                int `toml:"timeout_seconds"`
+	MaxAttempts                   int `toml:"max_attempts"`
+	MaxRetryWaitSeconds           int `toml:"max_retry_wait_seconds"`
+	CircuitBreakerThreshold       int `toml:"circuit_breaker_threshold"`
+	CircuitBreakerCooldownSeconds int `toml:"circuit_breaker_cooldown_seconds"`
+
+	OllamaURL            string                 `toml:"ollama_url" trusted:"true"`
+	OllamaModel          string                 `toml:"ollama_model"`
+	OllamaResponseFormat string                 `toml:"ollama_response_format"`
+	OllamaOptions        map[string]interface{} `toml:"ollama_options"`
+	OllamaKeepAlive      string                 `toml:"ollama_keep_alive"`
+	GroqAPIKey           string                 `toml:"groq_api_key" secret:"true"`
+	GroqModel            string                 `toml:"groq_model"`
+	GroqMaxTokens        int                    `toml:"groq_max_tokens"`
+	GroqResponseFormat   string                 `toml:"groq_response_format"`
+
+	OpenAIAPIKey         string `toml:"openai_api_key" secret:"true"`
+	OpenAIModel          string `toml:"openai_model"`
+	OpenAIOrganization   string `toml:"openai_organization"`
+	OpenAIBaseURL        string `toml:"openai_base_url" trusted:"true"`
+	OpenAIResponseFormat string `toml:"openai_response_format"`
+
+	AnthropicAPIKey    string `toml:"anthropic_api_key" secret:"true"`
+	AnthropicModel     string `toml:"anthropic_model"`
+	AnthropicMaxTokens int    `toml:"anthropic_max_tokens"`
+	AnthropicVersion   string `toml:"anthropic_version"`
+	AnthropicBaseURL   string `toml:"anthropic_base_url" trusted:"true"`
+
+	CompatibleBaseURL        string            `toml:"compatible_base_url" trusted:"true"`
+	CompatibleModel          string            `toml:"compatible_model"`
+	CompatibleAPIKey         string            `toml:"compatible_api_key" secret:"true"`
+	CompatibleAuthScheme     string            `toml:"compatible_auth_scheme"`
+	CompatibleHeaders        map[string]string `toml:"compatible_headers" secret:"true"`
+	CompatibleMaxTokens      int               `toml:"compatible_max_tokens"`
+	CompatibleResponseFormat string            `toml:"compatible_response_format"`
 }
 
 type PrinterConfig struct {
 	Kind string `toml:"kind"`
 }
-
-func LoadConfig(filepath string) (*Config, error) {
-	config := &Config{}
-	tree, err := toml.LoadFile(filepath)
-	if err != nil {
-		return nil, err
-	}
-	err = tree.Unmarshal(config)
-	if err != nil {
-		return nil, err
-	}
-	return config, nil
-}
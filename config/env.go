@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const envPrefix = "CODECRITIQUE_"
+
+// fallbackEnv lists the conventional variables that fill a key when neither
+// the configuration file nor a CODECRITIQUE_* variable set it. Git tokens
+// depend on the provider.
+var fallbackEnv = map[string][]string{
+	"ai.groq_api_key":      {"GROQ_API_KEY"},
+	"ai.openai_api_key":    {"OPENAI_API_KEY"},
+	"ai.anthropic_api_key": {"ANTHROPIC_API_KEY"},
+}
+
+var gitTokenEnv = map[string][]string{
+	"GitHub":          {"GITHUB_TOKEN", "GH_TOKEN"},
+	"GitLab":          {"GITLAB_TOKEN"},
+	"Bitbucket":       {"BITBUCKET_TOKEN"},
+	"BitbucketServer": {"BITBUCKET_TOKEN"},
+	"Gitea":           {"GITEA_TOKEN"},
+	"AzureDevOps":     {"AZURE_DEVOPS_EXT_PAT", "SYSTEM_ACCESSTOKEN"},
+}
+
+// GitTokenFromEnv returns the token for a Git provider from its
+// conventional variables, such as GITHUB_TOKEN, along with the name of the
+// variable that held it. Both are empty when none is set.
+func GitTokenFromEnv(provider string) (string, string) {
+	for _, name := range gitTokenEnv[provider] {
+		if value := os.Getenv(name); value != "" {
+			return value, name
+		}
+	}
+	return "", ""
+}
+
+// EnvName returns the variable that overrides a key, such as
+// CODECRITIQUE_GIT_TOKEN for "git.token".
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// applyEnv overrides every key of cfg that is set through its
+// CODECRITIQUE_* variable, or through the _FILE variant of it naming a file
+// that holds the value. Empty keys are then filled from the conventional
+// variables of the providers. Values that do not parse are recorded as
+// problems.
+func applyEnv(cfg *Config, sources *Sources) error {
+	err := walkKeys(cfg, func(key string, field reflect.Value) error {
+		name, value, ok, err := lookupEnv(EnvName(key))
+		if err != nil || !ok {
+			return err
+		}
+		source := Source{Name: "env " + name}
+		if err := setField(field, value); err != nil {
+			sources.problems = append(sources.problems, Problem{Key: key, Source: source, Message: fmt.Sprintf("invalid value: %s", err)})
+			return nil
+		}
+		sources.keys[key] = source
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return walkKeys(cfg, func(key string, field reflect.Value) error {
+		names := fallbackEnv[key]
+		if key == "git.token" {
+			names = gitTokenEnv[cfg.Git.Provider]
+		}
+		if !field.IsZero() {
+			return nil
+		}
+		for _, name := range names {
+			if value := os.Getenv(name); value != "" {
+				sources.keys[key] = Source{Name: "env " + name}
+				return setField(field, value)
+			}
+		}
+		return nil
+	})
+}
+
+// lookupEnv reads a variable, preferring its _FILE variant so secrets can
+// be mounted as files. It returns the name of the variable that was used.
+func lookupEnv(name string) (string, string, bool, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return name + "_FILE", strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+
+	value, ok := os.LookupEnv(name)
+	return name, value, ok, nil
+}
+
+// walkKeys calls fn for every key of the configuration, named
+// "section.key" after the TOML tags.
+func walkKeys(cfg *Config, fn func(key string, field reflect.Value) error) error {
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Type().Field(i).Tag.Get("toml")
+		fields := sections.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			key := section + "." + fields.Type().Field(j).Tag.Get("toml")
+			if err := fn(key, fields.Field(j)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setField parses value into the type of field. Maps are written as
+// "key=value,key=value" and lists as "a,b".
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", pair)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), mapValue(field.Type().Elem(), strings.TrimSpace(v)))
+		}
+		field.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+// mapValue converts the value of a map entry. Maps of arbitrary values, such
+// as model options, get numbers and booleans rather than strings.
+func mapValue(elem reflect.Type, value string) reflect.Value {
+	if elem.Kind() != reflect.Interface {
+		return reflect.ValueOf(value)
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return reflect.ValueOf(n)
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return reflect.ValueOf(f)
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		return reflect.ValueOf(b)
+	}
+	return reflect.ValueOf(value)
+}
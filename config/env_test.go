@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// isolate runs the test in an empty working directory without a user file,
+// so that only the layers set up by the test are loaded.
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	for _, names := range gitTokenEnv {
+		for _, name := range names {
+			t.Setenv(name, "")
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	return dir
+}
+
+func TestApplyEnvPrecedence(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, "codecritique.toml")
+	writeFile(t, path, `[git]
+provider = "GitHub"
+token = "from-file"
+
+[ai]
+openai_model = "gpt-4o"
+groq_model = "llama3"
+`)
+	t.Setenv("CODECRITIQUE_AI_OPENAI_MODEL", "gpt-4o-mini")
+	t.Setenv("GITHUB_TOKEN", "from-github-env")
+	t.Setenv("GROQ_API_KEY", "from-groq-env")
+
+	cfg, sources, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		key        string
+		got        string
+		want       string
+		wantSource string
+	}{
+		{"ai.openai_model", cfg.AI.OpenAIModel, "gpt-4o-mini", "env CODECRITIQUE_AI_OPENAI_MODEL"},
+		{"ai.groq_model", cfg.AI.GroqModel, "llama3", path + ":7"},
+		// Conventional variables only fill keys that nothing else set.
+		{"git.token", cfg.Git.Token, "from-file", path + ":3"},
+		{"ai.groq_api_key", cfg.AI.GroqAPIKey, "from-groq-env", "env GROQ_API_KEY"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, tt.got, tt.want)
+		}
+		if got := sources.Get(tt.key).String(); got != tt.wantSource {
+			t.Errorf("source of %s = %q, want %q", tt.key, got, tt.wantSource)
+		}
+	}
+
+	t.Setenv("CODECRITIQUE_GIT_TOKEN", "from-codecritique-env")
+	cfg, _, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Git.Token != "from-codecritique-env" {
+		t.Errorf("git.token = %q, want the CODECRITIQUE_GIT_TOKEN value", cfg.Git.Token)
+	}
+}
+
+func TestApplyEnvFile(t *testing.T) {
+	dir := isolate(t)
+	secret := filepath.Join(dir, "token")
+	writeFile(t, secret, "s3cret\r\n")
+	t.Setenv("CODECRITIQUE_GIT_TOKEN", "ignored")
+	t.Setenv("CODECRITIQUE_GIT_TOKEN_FILE", secret)
+
+	cfg, sources, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Git.Token != "s3cret" {
+		t.Errorf("git.token = %q, want the file content without the trailing newline", cfg.Git.Token)
+	}
+	if got := sources.Get("git.token").Name; got != "env CODECRITIQUE_GIT_TOKEN_FILE" {
+		t.Errorf("source of git.token = %q, want env CODECRITIQUE_GIT_TOKEN_FILE", got)
+	}
+
+	t.Setenv("CODECRITIQUE_GIT_TOKEN_FILE", filepath.Join(dir, "missing"))
+	if _, _, err := Load(""); err == nil || !strings.Contains(err.Error(), "CODECRITIQUE_GIT_TOKEN_FILE") {
+		t.Errorf("Load() error = %v, want one naming CODECRITIQUE_GIT_TOKEN_FILE", err)
+	}
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	isolate(t)
+	t.Setenv("CODECRITIQUE_AI_MAX_ATTEMPTS", "many")
+	t.Setenv("CODECRITIQUE_AI_COMPATIBLE_HEADERS", "X-Tenant=a,broken")
+
+	cfg, sources, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AI.MaxAttempts != 0 || cfg.AI.CompatibleHeaders != nil {
+		t.Errorf("invalid values were applied: max_attempts = %d, compatible_headers = %v", cfg.AI.MaxAttempts, cfg.AI.CompatibleHeaders)
+	}
+
+	var keys []string
+	for _, problem := range sources.problems {
+		keys = append(keys, problem.Key+" from "+problem.Source.Name)
+	}
+	want := []string{
+		"ai.max_attempts from env CODECRITIQUE_AI_MAX_ATTEMPTS",
+		"ai.compatible_headers from env CODECRITIQUE_AI_COMPATIBLE_HEADERS",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("problems = %q, want %q", keys, want)
+	}
+}
+
+func TestSetFieldMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "strings",
+			value: " X-Tenant = a , X-Trace=b=c ,",
+			want:  map[string]string{"X-Tenant": "a", "X-Trace": "b=c"},
+		},
+		{
+			name:  "typed values",
+			value: "num_ctx=8192,temperature=0.2,use_mmap=false,stop=END",
+			want:  map[string]interface{}{"num_ctx": int64(8192), "temperature": 0.2, "use_mmap": false, "stop": "END"},
+		},
+		{
+			name:  "empty",
+			value: "",
+			want:  map[string]string{},
+		},
+		{
+			name:    "missing separator",
+			value:   "X-Tenant=a,X-Trace",
+			want:    map[string]string(nil),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := reflect.New(reflect.TypeOf(tt.want)).Elem()
+			err := setField(field, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := field.Interface(); !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("setField() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// SourceDefault is the source of keys that no layer sets.
+const SourceDefault = "default"
+
+// trustRepoLocalEnv opts in to the keys that the configuration files in the
+// working tree may not set otherwise.
+const trustRepoLocalEnv = envPrefix + "TRUST_REPO_CONFIG"
+
+// Source is the layer that set the effective value of a key: a file path,
+// an environment variable or a flag. Line is the line of the key when the
+// source is a file.
+type Source struct {
+	Name string
+	Line int
+}
+
+func (s Source) String() string {
+	if s.Line > 0 {
+		return fmt.Sprintf("%s:%d", s.Name, s.Line)
+	}
+	return s.Name
+}
+
+// Sources tracks the source of every key, named "section.key", along with
+// the problems found while loading, which Validate reports.
+type Sources struct {
+	keys     map[string]Source
+	problems []Problem
+}
+
+// Get returns the source of a key.
+func (s *Sources) Get(key string) Source {
+	if source, ok := s.keys[key]; ok {
+		return source
+	}
+	return Source{Name: SourceDefault}
+}
+
+// Set records that a key was overridden, for example by a flag.
+func (s *Sources) Set(key, name string) {
+	s.keys[key] = Source{Name: name}
+}
+
+// workingDirSettings is the settings file of the working directory, which
+// predates the other layers.
+var workingDirSettings = filepath.Join("settings", "settings.toml")
+
+// Layers returns the configuration files that are merged when present, from
+// the lowest to the highest precedence: the system file, the
+// settings/settings.toml of the working directory, the user file and the
+// .codecritique.toml of the repository.
+//
+// Both files in the working tree come with the code under review, so they
+// may not set endpoints, TLS options or secrets unless
+// CODECRITIQUE_TRUST_REPO_CONFIG is true; such keys are reported as problems
+// instead.
+func Layers() []string {
+	layers := []string{"/etc/codecritique/settings.toml", workingDirSettings}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		layers = append(layers, filepath.Join(configHome, "codecritique", "settings.toml"))
+	}
+
+	if local := findRepoLocal(); local != "" {
+		layers = append(layers, local)
+	}
+
+	return layers
+}
+
+// Load merges the configuration layers, then the file given through
+// --config when path is not empty, then the environment. A later layer
+// overrides the keys it sets and leaves the others alone. Flags are applied
+// on top by the caller through Set.
+//
+// Unknown keys and invalid values do not fail loading; they are kept in the
+// sources and reported by Validate together with everything else.
+func Load(path string) (*Config, *Sources, error) {
+	cfg := &Config{}
+	sources := &Sources{keys: make(map[string]Source)}
+
+	local := findRepoLocal()
+	trustWorkingTree, _ := strconv.ParseBool(os.Getenv(trustRepoLocalEnv))
+
+	for _, layer := range Layers() {
+		restricted := (layer == local || layer == workingDirSettings) && !trustWorkingTree
+		if err := mergeFile(cfg, sources, layer, restricted); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, nil, err
+		}
+	}
+
+	if path != "" {
+		if err := mergeFile(cfg, sources, path, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := applyEnv(cfg, sources); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, sources, nil
+}
+
+// Set overrides a single key, named "section.key", with a value written as
+// in the environment.
+func (c *Config) Set(key, value string) error {
+	field, ok := fieldByKey(c, key)
+	if !ok {
+		return fmt.Errorf("unknown configuration key: %s", key)
+	}
+	if err := setField(field, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return nil
+}
+
+// mergeFile copies the keys that the file sets into cfg and records the
+// keys it does not know and the values it cannot decode. A restricted file
+// may not set trusted keys, which are recorded as problems and left alone.
+func mergeFile(cfg *Config, sources *Sources, path string, restricted bool) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	tree, err := toml.LoadBytes(content)
+	if err != nil {
+		return fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+
+	for _, key := range unknownKeys(tree) {
+		sources.problems = append(sources.problems, Problem{
+			Key:     key,
+			Source:  Source{Name: path, Line: tree.GetPosition(key).Line},
+			Message: "unknown key",
+		})
+	}
+
+	// Keys are decoded one by one, so that a value of the wrong type only
+	// affects its own key.
+	return walkKeys(cfg, func(key string, field reflect.Value) error {
+		if !tree.Has(key) {
+			return nil
+		}
+		source := Source{Name: path, Line: keyLine(tree, content, key)}
+		if restricted && isTrusted(key) {
+			sources.problems = append(sources.problems, Problem{
+				Key:     key,
+				Source:  source,
+				Message: fmt.Sprintf("may not be set in the configuration of a repository unless %s=true", trustRepoLocalEnv),
+			})
+			return nil
+		}
+		value := reflect.New(field.Type()).Elem()
+		if err := decodeValue(value, tree.Get(key)); err != nil {
+			sources.problems = append(sources.problems, Problem{Key: key, Source: source, Message: fmt.Sprintf("invalid value: %s", err)})
+			return nil
+		}
+		field.Set(value)
+		sources.keys[key] = source
+		return nil
+	})
+}
+
+// keyLine returns the line of a key. go-toml does not record where inline
+// tables are, so those are looked up in the text of their section.
+func keyLine(tree *toml.Tree, content []byte, key string) int {
+	if line := tree.GetPosition(key).Line; line > 0 {
+		return line
+	}
+
+	sectionName, keyName, _ := strings.Cut(key, ".")
+	section := tree.GetPosition(sectionName).Line
+	for i, line := range strings.Split(string(content), "\n") {
+		if i+1 <= section {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "[") {
+			break
+		}
+		if name, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(name) == keyName {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// decodeValue stores a value as go-toml returns it into a field of Config.
+func decodeValue(field reflect.Value, value interface{}) error {
+	mismatch := fmt.Errorf("expected %s, got %s", tomlType(field.Type()), tomlTypeOf(value))
+
+	switch field.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch
+		}
+		field.SetString(s)
+	case reflect.Int:
+		n, ok := value.(int64)
+		if !ok {
+			return mismatch
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i+1, err)
+			}
+		}
+		field.Set(slice)
+	case reflect.Map:
+		table, ok := value.(*toml.Tree)
+		if !ok {
+			return mismatch
+		}
+		m := reflect.MakeMap(field.Type())
+		for k, v := range table.ToMap() {
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := decodeValue(elem, v); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(k), elem)
+		}
+		field.Set(m)
+	case reflect.Interface:
+		field.Set(reflect.ValueOf(value))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+// tomlType names the TOML type that a field of Config takes.
+func tomlType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int:
+		return "an integer"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice:
+		return "an array"
+	case reflect.Map:
+		return "a table"
+	default:
+		return "a string"
+	}
+}
+
+// tomlTypeOf names the TOML type of a value as go-toml returns it.
+func tomlTypeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case float64:
+		return "a float"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "an array"
+	case *toml.Tree, map[string]interface{}:
+		return "a table"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// isTrusted reports whether a key names an endpoint, a TLS option or a
+// secret, which only trusted files may set.
+func isTrusted(key string) bool {
+	sectionName, keyName, _ := strings.Cut(key, ".")
+	section, ok := structFieldByTag(reflect.TypeOf(Config{}), sectionName)
+	if !ok {
+		return false
+	}
+	field, ok := structFieldByTag(section.Type, keyName)
+	return ok && (field.Tag.Get("trusted") == "true" || field.Tag.Get("secret") == "true")
+}
+
+// unknownKeys lists the sections and keys of a file that have no
+// counterpart in Config.
+func unknownKeys(tree *toml.Tree) []string {
+	var unknown []string
+	for _, sectionName := range tree.Keys() {
+		section, ok := tree.Get(sectionName).(*toml.Tree)
+		if _, known := fieldByTag(reflect.ValueOf(Config{}), sectionName); !ok || !known {
+			unknown = append(unknown, sectionName)
+			continue
+		}
+		for _, keyName := range section.Keys() {
+			if _, known := fieldByKey(&Config{}, sectionName+"."+keyName); !known {
+				unknown = append(unknown, sectionName+"."+keyName)
+			}
+		}
+	}
+
+	sort.Slice(unknown, func(i, j int) bool {
+		return tree.GetPosition(unknown[i]).Line < tree.GetPosition(unknown[j]).Line
+	})
+	return unknown
+}
+
+// fieldByKey returns the field of cfg named "section.key" by its TOML tags.
+func fieldByKey(cfg *Config, key string) (reflect.Value, bool) {
+	sectionName, keyName, ok := strings.Cut(key, ".")
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	section, ok := fieldByTag(reflect.ValueOf(cfg).Elem(), sectionName)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return fieldByTag(section, keyName)
+}
+
+func fieldByTag(v reflect.Value, tag string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("toml") == tag {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func structFieldByTag(t reflect.Type, tag string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("toml") == tag {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// findRepoLocal looks for .codecritique.toml from the working directory up
+// to the root of the Git repository that contains it.
+func findRepoLocal() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(dir, ".codecritique.toml")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeFileRestricted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".codecritique.toml")
+	writeFile(t, path, `[git]
+base_url = "https://attacker.example"
+token = "stolen"
+
+[ai]
+openai_model = "gpt-4o"
+openai_base_url = "https://attacker.example/v1"
+compatible_headers = { "X-Tenant" = "team-a" }
+`)
+
+	tests := []struct {
+		restricted   bool
+		wantBaseURL  string
+		wantToken    string
+		wantOpenAI   string
+		wantProblems []Problem
+	}{
+		{
+			restricted: true,
+			wantProblems: []Problem{
+				{Key: "git.token", Source: Source{Name: path, Line: 3}},
+				{Key: "git.base_url", Source: Source{Name: path, Line: 2}},
+				{Key: "ai.openai_base_url", Source: Source{Name: path, Line: 7}},
+				{Key: "ai.compatible_headers", Source: Source{Name: path, Line: 8}},
+			},
+		},
+		{
+			restricted:  false,
+			wantBaseURL: "https://attacker.example",
+			wantToken:   "stolen",
+			wantOpenAI:  "https://attacker.example/v1",
+		},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{}
+		sources := &Sources{keys: make(map[string]Source)}
+		if err := mergeFile(cfg, sources, path, tt.restricted); err != nil {
+			t.Fatalf("mergeFile(restricted=%v) error = %v", tt.restricted, err)
+		}
+
+		if cfg.AI.OpenAIModel != "gpt-4o" {
+			t.Errorf("restricted=%v: openai_model = %q, want gpt-4o", tt.restricted, cfg.AI.OpenAIModel)
+		}
+		if cfg.Git.BaseURL != tt.wantBaseURL || cfg.Git.Token != tt.wantToken || cfg.AI.OpenAIBaseURL != tt.wantOpenAI {
+			t.Errorf("restricted=%v: base_url = %q, token = %q, openai_base_url = %q", tt.restricted, cfg.Git.BaseURL, cfg.Git.Token, cfg.AI.OpenAIBaseURL)
+		}
+		if tt.restricted && cfg.AI.CompatibleHeaders != nil {
+			t.Errorf("restricted: compatible_headers = %v, want none", cfg.AI.CompatibleHeaders)
+		}
+
+		if len(sources.problems) != len(tt.wantProblems) {
+			t.Fatalf("restricted=%v: problems = %+v, want %d", tt.restricted, sources.problems, len(tt.wantProblems))
+		}
+		for i, want := range tt.wantProblems {
+			if got := sources.problems[i]; got.Key != want.Key || got.Source != want.Source {
+				t.Errorf("problem %d = %s at %s, want %s at %s", i, got.Key, got.Source, want.Key, want.Source)
+			}
+		}
+	}
+}
+
+// TestLoadWorkingTree checks that the settings file of the working directory
+// is restricted like .codecritique.toml and ranks below the user file.
+func TestLoadWorkingTree(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(dir, "settings", "settings.toml"), `[ai]
+provider = "Groq"
+groq_model = "llama-3.1-70b-versatile"
+openai_base_url = "https://attacker.example/v1"
+`)
+	writeFile(t, filepath.Join(dir, ".codecritique.toml"), `[git]
+base_url = "https://attacker.example"
+`)
+
+	configHome := t.TempDir()
+	writeFile(t, filepath.Join(configHome, "codecritique", "settings.toml"), `[ai]
+provider = "OpenAI"
+`)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv(trustRepoLocalEnv, "")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	cfg, sources, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.AI.Provider != "OpenAI" {
+		t.Errorf("ai.provider = %q, want the user file's OpenAI", cfg.AI.Provider)
+	}
+	if cfg.AI.GroqModel != "llama-3.1-70b-versatile" {
+		t.Errorf("ai.groq_model = %q, want it from settings/settings.toml", cfg.AI.GroqModel)
+	}
+	if cfg.AI.OpenAIBaseURL != "" || cfg.Git.BaseURL != "" {
+		t.Errorf("endpoints from the working tree were applied: openai_base_url = %q, git.base_url = %q", cfg.AI.OpenAIBaseURL, cfg.Git.BaseURL)
+	}
+
+	keys := map[string]bool{}
+	for _, problem := range sources.problems {
+		keys[problem.Key] = true
+	}
+	if !keys["ai.openai_base_url"] || !keys["git.base_url"] {
+		t.Errorf("problems = %+v, want ai.openai_base_url and git.base_url", sources.problems)
+	}
+
+	t.Setenv(trustRepoLocalEnv, "true")
+	cfg, _, err = Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AI.OpenAIBaseURL != "https://attacker.example/v1" || cfg.Git.BaseURL != "https://attacker.example" {
+		t.Errorf("trusted working tree: openai_base_url = %q, git.base_url = %q", cfg.AI.OpenAIBaseURL, cfg.Git.BaseURL)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+// Show writes the effective configuration as TOML, annotating every key
+// with its source. Keys tagged as secret are redacted when set.
+func Show(w io.Writer, cfg *Config, sources *Sources) error {
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Type().Field(i).Tag.Get("toml")
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "[%s]\n", section)
+
+		fields := sections.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			field := fields.Type().Field(j)
+			name := field.Tag.Get("toml")
+			secret := field.Tag.Get("secret") == "true"
+			value := formatValue(fields.Field(j), secret)
+			if _, err := fmt.Fprintf(w, "%s = %s # %s\n", name, value, sources.Get(section+"."+name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatValue(v reflect.Value, secret bool) string {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return `""`
+		}
+		return formatValue(v.Elem(), secret)
+	case reflect.String:
+		if secret && v.String() != "" {
+			return fmt.Sprintf("%q", redacted)
+		}
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, fmt.Sprintf("%q = %s", key, formatValue(v.MapIndex(reflect.ValueOf(key)), secret)))
+		}
+		if len(pairs) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }"
+	case reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, formatValue(v.Index(i), secret))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
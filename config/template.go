@@ -0,0 +1,38 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"io"
+	"strings"
+)
+
+//go:embed template.toml
+var template []byte
+
+// WriteTemplate writes a starter configuration file with every key and a
+// short description of it. For the files in a working tree, the keys that
+// they may not set are commented out.
+func WriteTemplate(w io.Writer, repoLocal bool) error {
+	if !repoLocal {
+		_, err := w.Write(template)
+		return err
+	}
+
+	var buf bytes.Buffer
+	var section string
+	scanner := bufio.NewScanner(bytes.NewReader(template))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[]")
+		} else if key, _, ok := strings.Cut(line, "="); ok && isTrusted(section+"."+strings.TrimSpace(key)) {
+			line = "# " + line
+		}
+		buf.WriteString(line + "\n")
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Problem is a single issue with the configuration.
+type Problem struct {
+	Key     string
+	Source  Source
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Source.Name == SourceDefault {
+		return fmt.Sprintf("%s: %s", p.Key, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Source, p.Key, p.Message)
+}
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	count := "1 problem"
+	if len(e.Problems) != 1 {
+		count = fmt.Sprintf("%d problems", len(e.Problems))
+	}
+	lines = append(lines, fmt.Sprintf("invalid configuration (%s):", count))
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	gitProviders    = []string{"GitHub", "GitLab", "Bitbucket", "BitbucketServer", "Gitea", "AzureDevOps"}
+	aiProviders     = []string{"Anthropic", "Groq", "Ollama", "OpenAI", "OpenAICompatible"}
+	authSchemes     = []string{"bearer", "api-key", "none"}
+	responseFormats = []string{"json_schema", "json_object", "none"}
+	printerKinds    = []string{"json", "html", "markdown"}
+)
+
+// aiRequired lists the keys each AI provider cannot work without.
+var aiRequired = map[string][]string{
+	"Anthropic":        {"anthropic_api_key", "anthropic_model"},
+	"Groq":             {"groq_api_key", "groq_model"},
+	"Ollama":           {"ollama_model"},
+	"OpenAI":           {"openai_api_key", "openai_model"},
+	"OpenAICompatible": {"compatible_base_url", "compatible_model"},
+}
+
+// Validate checks the configuration as loaded with sources and returns a
+// *ValidationError listing every problem, including the unknown keys and
+// invalid values found by Load. The Git keys are only checked when a Git
+// provider is set, since local and patch reviews do not need one.
+func Validate(cfg *Config, sources *Sources) error {
+	v := &validator{cfg: cfg, sources: sources}
+	v.problems = append(v.problems, sources.problems...)
+
+	if cfg.Git.Provider != "" {
+		v.oneOf("git.provider", cfg.Git.Provider, gitProviders)
+		switch cfg.Git.Provider {
+		case "BitbucketServer", "Gitea":
+			v.required("git.base_url")
+		case "AzureDevOps":
+			v.required("git.token")
+		}
+		if cfg.Git.Username != "" {
+			v.required("git.token")
+		}
+		v.url("git.base_url")
+		v.url("git.upload_url")
+		if cfg.Git.CAFile != "" {
+			if _, err := os.Stat(cfg.Git.CAFile); err != nil {
+				v.add("git.ca_file", fmt.Sprintf("cannot read CA bundle: %s", err))
+			}
+		}
+	}
+
+	v.required("ai.provider")
+	v.oneOf("ai.provider", cfg.AI.Provider, aiProviders)
+	providers := []string{cfg.AI.Provider}
+	seen := map[string]bool{cfg.AI.Provider: true}
+	for _, provider := range cfg.AI.FallbackProviders {
+		v.oneOf("ai.fallback_providers", provider, aiProviders)
+		if seen[provider] {
+			v.add("ai.fallback_providers", fmt.Sprintf("%s is listed more than once", provider))
+			continue
+		}
+		seen[provider] = true
+		providers = append(providers, provider)
+	}
+	for _, provider := range providers {
+		for _, key := range aiRequired[provider] {
+			v.required("ai." + key)
+		}
+	}
+	v.url("ai.ollama_url")
+	v.url("ai.openai_base_url")
+	v.url("ai.anthropic_base_url")
+	v.url("ai.compatible_base_url")
+	v.oneOf("ai.compatible_auth_scheme", strings.ToLower(cfg.AI.CompatibleAuthScheme), authSchemes)
+	for _, key := range []string{"ai.ollama_response_format", "ai.groq_response_format", "ai.openai_response_format", "ai.compatible_response_format"} {
+		v.oneOf(key, strings.ToLower(v.value(key).String()), responseFormats)
+	}
+	for _, key := range []string{
+		"ai.max_prompt_tokens", "ai.groq_max_tokens", "ai.anthropic_max_tokens", "ai.compatible_max_tokens",
+		"ai.timeout_seconds", "ai.max_attempts", "ai.max_retry_wait_seconds",
+		"ai.circuit_breaker_threshold", "ai.circuit_breaker_cooldown_seconds",
+	} {
+		v.nonNegative(key)
+	}
+
+	v.required("printer.kind")
+	v.oneOf("printer.kind", cfg.Printer.Kind, printerKinds)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+type validator struct {
+	cfg      *Config
+	sources  *Sources
+	problems []Problem
+}
+
+func (v *validator) add(key, message string) {
+	v.problems = append(v.problems, Problem{Key: key, Source: v.sources.Get(key), Message: message})
+}
+
+func (v *validator) value(key string) reflect.Value {
+	field, _ := fieldByKey(v.cfg, key)
+	return field
+}
+
+func (v *validator) required(key string) {
+	if v.value(key).IsZero() {
+		v.add(key, fmt.Sprintf("is required (set it in a config file or through %s)", EnvName(key)))
+	}
+}
+
+// oneOf accepts empty values, which required reports where they matter.
+func (v *validator) oneOf(key, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	v.add(key, fmt.Sprintf("unsupported value %q, expected one of: %s", value, strings.Join(allowed, ", ")))
+}
+
+func (v *validator) url(key string) {
+	raw := v.value(key).String()
+	if raw == "" {
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(key, fmt.Sprintf("invalid URL %q, expected an absolute http or https URL", raw))
+	}
+}
+
+func (v *validator) nonNegative(key string) {
+	if v.value(key).Int() < 0 {
+		v.add(key, "must not be negative")
+	}
+}
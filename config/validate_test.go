@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := isolate(t)
+	t.Setenv("GROQ_API_KEY", "")
+	path := filepath.Join(dir, "codecritique.toml")
+	writeFile(t, path, `[git]
+provider = "GitHub"
+base_url = "github.example.com"
+
+[ai]
+provider = "Ollama"
+fallback_providers = ["Groq", "Ollama"]
+max_attempts = "three"
+ollama_modle = "llama3"
+
+[printer]
+kind = "pdf"
+`)
+
+	cfg, sources, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	err = Validate(cfg, sources)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want a *ValidationError", err)
+	}
+
+	want := strings.ReplaceAll(`invalid configuration (8 problems):
+  FILE:9: ai.ollama_modle: unknown key
+  FILE:8: ai.max_attempts: invalid value: expected an integer, got a string
+  FILE:3: git.base_url: invalid URL "github.example.com", expected an absolute http or https URL
+  FILE:7: ai.fallback_providers: Ollama is listed more than once
+  ai.ollama_model: is required (set it in a config file or through CODECRITIQUE_AI_OLLAMA_MODEL)
+  ai.groq_api_key: is required (set it in a config file or through CODECRITIQUE_AI_GROQ_API_KEY)
+  ai.groq_model: is required (set it in a config file or through CODECRITIQUE_AI_GROQ_MODEL)
+  FILE:12: printer.kind: unsupported value "pdf", expected one of: json, html, markdown`, "FILE", path)
+	if got := verr.Error(); got != want {
+		t.Errorf("Validate() error =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidateLocalReview(t *testing.T) {
+	cfg := &Config{}
+	cfg.AI.Provider = "Ollama"
+	cfg.AI.OllamaModel = "llama3"
+	cfg.Printer.Kind = "json"
+	sources := &Sources{keys: map[string]Source{"ai.ollama_model": {Name: "env CODECRITIQUE_AI_OLLAMA_MODEL"}}}
+
+	if err := Validate(cfg, sources); err != nil {
+		t.Fatalf("Validate() error = %v, want none without a Git provider", err)
+	}
+
+	cfg.AI.OllamaURL = "localhost:11434"
+	want := `invalid configuration (1 problem):
+  ai.ollama_url: invalid URL "localhost:11434", expected an absolute http or https URL`
+	if err := Validate(cfg, sources); err == nil || err.Error() != want {
+		t.Errorf("Validate() error = %v, want\n%s", err, want)
+	}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 type fetcher interface {
-	FetchPullRequest(ctx context.Context, owner, repo, number string) (*model.PullRequest, error)
+	FetchPullRequest(ctx context.Context, repository, number string) (*model.PullRequest, error)
 }
 
 type reviewer interface {
@@ -19,10 +19,15 @@ type printer interface {
 	Print(*model.Review) error
 }
 
+type publisher interface {
+	PublishReview(ctx context.Context, repository, number string, review *model.Review) error
+}
+
 type Critique struct {
-	fetcher  fetcher
-	reviewer reviewer
-	printer  printer
+	fetcher   fetcher
+	reviewer  reviewer
+	printer   printer
+	publisher publisher
 }
 
 func New(
@@ -37,12 +42,19 @@ func New(
 	}
 }
 
+// WithPublisher makes Criticize post the review back to the pull request
+// after printing it.
+func (c *Critique) WithPublisher(publisher publisher) *Critique {
+	c.publisher = publisher
+	return c
+}
+
 func (c *Critique) Criticize(
 	ctx context.Context,
-	owner, repo, number string,
+	repository, number string,
 ) error {
 	// Fetch the pull request
-	pr, err := c.fetcher.FetchPullRequest(ctx, owner, repo, number)
+	pr, err := c.fetcher.FetchPullRequest(ctx, repository, number)
 	if err != nil {
 		return fmt.Errorf("failed to fetch pull request: %w", err)
 	}
@@ -52,11 +64,19 @@ func (c *Critique) Criticize(
 	if err != nil {
 		return fmt.Errorf("failed to review pull request: %w", err)
 	}
+	review.Metadata.Warnings = append(review.Metadata.Warnings, pr.Warnings...)
 
 	// Print the review
 	if err := c.printer.Print(review); err != nil {
 		return fmt.Errorf("could not print review: %w", err)
 	}
 
+	// Publish the review
+	if c.publisher != nil {
+		if err := c.publisher.PublishReview(ctx, repository, number, review); err != nil {
+			return fmt.Errorf("could not publish review: %w", err)
+		}
+	}
+
 	return nil
 }
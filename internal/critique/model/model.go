@@ -1,10 +1,86 @@
 package model
 
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
 type PullRequest struct {
 	Title       string
 	Branch      string
 	Description string
-	Diff        string
+	// HeadSHA is the commit the files were fetched at, so that a review
+	// is published against the changes that were reviewed even if the
+	// pull request was updated in the meantime. It is empty for local
+	// changes and patches.
+	HeadSHA string
+	// BaseSHA and StartSHA are the merge base and the commit of the target
+	// branch the diff was computed from. Only GitLab reports them; it needs
+	// all three commits to anchor comments.
+	BaseSHA  string
+	StartSHA string
+	Files    []File
+	// Warnings describe parts of the pull request that could not be
+	// fetched completely.
+	Warnings []string
+}
+
+type FileStatus string
+
+const (
+	FileAdded    FileStatus = "added"
+	FileModified FileStatus = "modified"
+	FileRenamed  FileStatus = "renamed"
+	FileDeleted  FileStatus = "deleted"
+	FileBinary   FileStatus = "binary"
+)
+
+// File is the diff of a single file. OldPath is empty for added files and
+// NewPath is empty for deleted ones.
+type File struct {
+	OldPath string
+	NewPath string
+	Status  FileStatus
+	Hunks   []Hunk
+}
+
+// Path returns the path the file is known by after the change.
+func (f File) Path() string {
+	if f.NewPath == "" {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	// Section is the text after the closing "@@" of the hunk header,
+	// usually the enclosing function.
+	Section string
+	Lines   []Line
+}
+
+type LineKind string
+
+const (
+	LineContext LineKind = " "
+	LineAdded   LineKind = "+"
+	LineDeleted LineKind = "-"
+	// LineNoNewline is the "\ No newline at end of file" marker.
+	LineNoNewline LineKind = `\`
+)
+
+// Line is a single line of a hunk. OldLine is zero for added lines and
+// NewLine is zero for deleted ones.
+type Line struct {
+	Kind    LineKind
+	Content string
+	OldLine int
+	NewLine int
 }
 
 type Review struct {
@@ -18,6 +94,25 @@ type Review struct {
 	Testing           string       `json:"testing"`
 	EstimatedEffort   string       `json:"estimated_effort_to_review"`
 	CodeFeedback      []Feedback   `json:"code_feedback"`
+	Metadata          Metadata     `json:"metadata"`
+}
+
+// Metadata is filled in by codecritique itself rather than by the model.
+type Metadata struct {
+	// Provider and Model name the AI backend that produced the review.
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Warnings []string  `json:"warnings,omitempty"`
+	Attempts []Attempt `json:"attempts,omitempty"`
+}
+
+// Attempt is a single request to an AI provider.
+type Attempt struct {
+	Provider   string `json:"provider"`
+	Attempt    int    `json:"attempt"`
+	Status     int    `json:"status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
 }
 
 type CodeQuality struct {
@@ -30,3 +125,60 @@ type Feedback struct {
 	Line       *int   `json:"line,omitempty"`
 	Suggestion string `json:"suggestion"`
 }
+
+// UnmarshalJSON accepts the line as a number or as a string such as "13",
+// "L13" or a range like "13-15", which is anchored at its first line. Lines
+// that cannot be read, such as "N/A", are left out.
+func (f *Feedback) UnmarshalJSON(data []byte) error {
+	type feedback Feedback
+	var raw struct {
+		feedback
+		Line json.RawMessage `json:"line,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*f = Feedback(raw.feedback)
+	f.Line = parseLine(raw.Line)
+	return nil
+}
+
+// parseLine returns nil for a missing or null line and for lines below 1,
+// which cannot be anchored anywhere.
+func parseLine(raw json.RawMessage) *int {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var number float64
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return positiveLine(int(number))
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil
+	}
+
+	text = strings.TrimSpace(strings.ToLower(text))
+	text = strings.TrimPrefix(text, "line")
+	text = strings.TrimLeft(text, "l #:")
+	end := 0
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+
+	line, err := strconv.Atoi(text[:end])
+	if err != nil {
+		return nil
+	}
+	return positiveLine(line)
+}
+
+func positiveLine(line int) *int {
+	if line <= 0 {
+		return nil
+	}
+	return &line
+}
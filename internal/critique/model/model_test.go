@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFeedbackUnmarshalLine(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want int // 0 means no line
+	}{
+		{"number", `{"file": "a.go", "line": 13}`, 13},
+		{"string", `{"file": "a.go", "line": "13"}`, 13},
+		{"prefixed", `{"file": "a.go", "line": "L13"}`, 13},
+		{"word prefix", `{"file": "a.go", "line": "line 7"}`, 7},
+		{"range", `{"file": "a.go", "line": "13-15"}`, 13},
+		{"null", `{"file": "a.go", "line": null}`, 0},
+		{"missing", `{"file": "a.go"}`, 0},
+		{"zero", `{"file": "a.go", "line": 0}`, 0},
+		{"negative", `{"file": "a.go", "line": -3}`, 0},
+		{"not a number", `{"file": "a.go", "line": "N/A"}`, 0},
+		{"empty string", `{"file": "a.go", "line": ""}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var feedback Feedback
+			if err := json.Unmarshal([]byte(tt.json), &feedback); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if feedback.File != "a.go" {
+				t.Errorf("File = %q, want a.go", feedback.File)
+			}
+
+			switch {
+			case tt.want == 0 && feedback.Line != nil:
+				t.Errorf("Line = %d, want nil", *feedback.Line)
+			case tt.want != 0 && feedback.Line == nil:
+				t.Errorf("Line = nil, want %d", tt.want)
+			case tt.want != 0 && *feedback.Line != tt.want:
+				t.Errorf("Line = %d, want %d", *feedback.Line, tt.want)
+			}
+		})
+	}
+}
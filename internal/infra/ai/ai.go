@@ -1,14 +1,12 @@
 package ai
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"text/template"
 
@@ -16,195 +14,362 @@ import (
 	"github.com/holistic-engineering/codecritique/internal/critique/model"
 )
 
-//go:embed prompts/reviewer.prompt
+//go:embed prompts/reviewer.prompt prompts/summary.prompt prompts/repair.prompt
 var promptFS embed.FS
 
+const defaultMaxPromptTokens = 6000
+
 type Provider string
 
 const (
-	ProviderAnthropic Provider = "Anthropic"
-	ProviderGroq      Provider = "Groq"
-	ProviderOllama    Provider = "Ollama"
-	ProviderOpenAI    Provider = "OpenAI"
+	ProviderAnthropic        Provider = "Anthropic"
+	ProviderGroq             Provider = "Groq"
+	ProviderOllama           Provider = "Ollama"
+	ProviderOpenAI           Provider = "OpenAI"
+	ProviderOpenAICompatible Provider = "OpenAICompatible"
 )
 
 type Client struct {
-	provider         Provider
-	ollamaURL        string
-	ollamaModel      string
-	groqAPIKey       string
-	groqModel        string
-	reviewerTemplate *template.Template
+	// providers are tried in order until one of them produces a review.
+	providers          []Provider
+	ollamaURL          string
+	ollamaChat         bool
+	ollamaModel        string
+	ollamaFormat       ResponseFormat
+	ollamaOptions      map[string]interface{}
+	ollamaKeepAlive    string
+	groq               *chatEndpoint
+	openAI             *chatEndpoint
+	compatible         *chatEndpoint
+	anthropicAPIKey    string
+	anthropicModel     string
+	anthropicMaxTokens int
+	anthropicVersion   string
+	anthropicBaseURL   string
+	maxPromptTokens    int
+	http               *httpClient
+	reviewerTemplate   *template.Template
+	summaryTemplate    *template.Template
+	repairTemplate     *template.Template
 }
 
 func New(cfg *config.AIConfig) (*Client, error) {
-	reviewerPrompt, err := promptFS.ReadFile("prompts/reviewer.prompt")
+	tmpl, err := loadTemplate("reviewer.prompt")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		return nil, err
 	}
 
-	tmpl, err := template.New("reviewPrompt").Parse(string(reviewerPrompt))
+	summaryTmpl, err := loadTemplate("summary.prompt")
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+		return nil, err
 	}
 
-	return &Client{
-		provider:         Provider(cfg.Provider),
-		ollamaURL:        cfg.OllamaURL,
-		ollamaModel:      cfg.OllamaModel,
-		groqAPIKey:       cfg.GroqAPIKey,
-		groqModel:        cfg.GroqModel,
-		reviewerTemplate: tmpl,
-	}, nil
-}
+	repairTmpl, err := loadTemplate("repair.prompt")
+	if err != nil {
+		return nil, err
+	}
 
-func (c *Client) Review(ctx context.Context, pr *model.PullRequest) (*model.Review, error) {
-	switch c.provider {
-	case ProviderOllama:
-		return c.reviewWithOllama(ctx, pr)
-	case ProviderGroq:
-		return c.reviewWithGroq(ctx, pr)
-	case ProviderOpenAI, ProviderAnthropic:
-		return nil, fmt.Errorf("AI provider %s not implemented yet", c.provider)
-	default:
-		return nil, fmt.Errorf("unknown AI provider: %s", c.provider)
+	providers := []Provider{Provider(cfg.Provider)}
+	for _, fallback := range cfg.FallbackProviders {
+		providers = append(providers, Provider(fallback))
+	}
+	for _, provider := range providers {
+		switch provider {
+		case ProviderAnthropic, ProviderGroq, ProviderOllama, ProviderOpenAI, ProviderOpenAICompatible:
+		default:
+			return nil, fmt.Errorf("unknown AI provider: %s", provider)
+		}
 	}
-}
 
-func (c *Client) reviewWithOllama(ctx context.Context, pr *model.PullRequest) (*model.Review, error) {
-	prompt, err := c.generatePrompt(pr)
+	groq, err := newGroqEndpoint(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("could not generate prompt: %w", err)
+		return nil, err
 	}
 
-	requestBody, err := json.Marshal(map[string]string{
-		"model":  c.ollamaModel,
-		"prompt": prompt,
-	})
+	openAI, err := newOpenAIEndpoint(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.ollamaURL, bytes.NewBuffer(requestBody))
+	compatible, err := newCompatibleEndpoint(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	ollamaFormat, err := parseResponseFormat(cfg.OllamaResponseFormat, ResponseFormatJSONSchema)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request to Ollama: %w", err)
+		return nil, fmt.Errorf("invalid Ollama response format: %w", err)
+	}
+
+	anthropicMaxTokens := cfg.AnthropicMaxTokens
+	if anthropicMaxTokens <= 0 {
+		anthropicMaxTokens = defaultAnthropicMaxTokens
+	}
+
+	anthropicVersion := cfg.AnthropicVersion
+	if anthropicVersion == "" {
+		anthropicVersion = defaultAnthropicVersion
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama returned non-OK status: %s", resp.Status)
+	anthropicBaseURL := cfg.AnthropicBaseURL
+	if anthropicBaseURL == "" {
+		anthropicBaseURL = defaultAnthropicBaseURL
 	}
 
-	var fullResponse strings.Builder
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		var result map[string]interface{}
-		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
-			return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+	ollamaURL, ollamaChat := ollamaEndpoint(cfg.OllamaURL)
+
+	maxPromptTokens := cfg.MaxPromptTokens
+	if maxPromptTokens <= 0 {
+		maxPromptTokens = defaultMaxPromptTokens
+	}
+
+	return &Client{
+		providers:          providers,
+		ollamaURL:          ollamaURL,
+		ollamaChat:         ollamaChat,
+		ollamaModel:        cfg.OllamaModel,
+		ollamaFormat:       ollamaFormat,
+		ollamaOptions:      cfg.OllamaOptions,
+		ollamaKeepAlive:    cfg.OllamaKeepAlive,
+		groq:               groq,
+		openAI:             openAI,
+		compatible:         compatible,
+		anthropicAPIKey:    cfg.AnthropicAPIKey,
+		anthropicModel:     cfg.AnthropicModel,
+		anthropicMaxTokens: anthropicMaxTokens,
+		anthropicVersion:   anthropicVersion,
+		anthropicBaseURL:   strings.TrimSuffix(anthropicBaseURL, "/"),
+		maxPromptTokens:    maxPromptTokens,
+		http:               newHTTPClient(cfg),
+		reviewerTemplate:   tmpl,
+		summaryTemplate:    summaryTmpl,
+		repairTemplate:     repairTmpl,
+	}, nil
+}
+
+func loadTemplate(name string) (*template.Template, error) {
+	prompt, err := promptFS.ReadFile("prompts/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template %s: %w", name, err)
+	}
+
+	tmpl, err := template.New(name).Parse(string(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+	}
+	if tmpl.Lookup("system") == nil || tmpl.Lookup("user") == nil {
+		return nil, fmt.Errorf("prompt template %s must define a system and a user part", name)
+	}
+
+	return tmpl, nil
+}
+
+// prompt is a request to a model. The instructions go into the system
+// message, which models weigh above the user message, and the pull request
+// or whatever else the model works on into the user message.
+type prompt struct {
+	system string
+	user   string
+}
+
+func (p prompt) tokens() int {
+	return estimateTokens(p.system) + estimateTokens(p.user)
+}
+
+// executePrompt renders the "system" and "user" parts of a prompt template.
+func executePrompt(tmpl *template.Template, data interface{}) (prompt, error) {
+	var system, user bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+		return prompt{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&user, "user", data); err != nil {
+		return prompt{}, err
+	}
+
+	return prompt{system: system.String(), user: user.String()}, nil
+}
+
+// Review reviews the pull request with the first provider, falling back to
+// the next one when a provider is unavailable, rate limited, gets a prompt
+// that exceeds its context or answers with output that cannot be parsed.
+func (c *Client) Review(ctx context.Context, pr *model.PullRequest) (*model.Review, error) {
+	ctx, attempts := withRecorder(ctx)
+
+	var warnings []string
+	var errs []error
+	for i, provider := range c.providers {
+		review, err := c.review(ctx, provider, pr)
+		if err == nil {
+			review.Metadata.Provider = string(provider)
+			review.Metadata.Model = c.model(provider)
+			review.Metadata.Warnings = append(review.Metadata.Warnings, warnings...)
+			review.Metadata.Warnings = append(review.Metadata.Warnings, attempts.listWarnings()...)
+			review.Metadata.Attempts = attempts.list()
+			return review, nil
 		}
-		if response, ok := result["response"].(string); ok {
-			fullResponse.WriteString(response)
+
+		if len(c.providers) == 1 {
+			return nil, err
 		}
-		if done, ok := result["done"].(bool); ok && done {
+		errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+		if i == len(c.providers)-1 || ctx.Err() != nil || !canFallBack(err) {
 			break
 		}
+		warnings = append(warnings, fmt.Sprintf("%s could not review the pull request, %s was used instead: %s", provider, c.providers[i+1], err))
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading Ollama response: %w", err)
-	}
-
-	return c.parseResponse(fullResponse.String(), pr)
+	return nil, fmt.Errorf("no AI provider could review the pull request: %w", errors.Join(errs...))
 }
 
-func (c *Client) reviewWithGroq(ctx context.Context, pr *model.PullRequest) (*model.Review, error) {
-	prompt, err := c.generatePrompt(pr)
+func (c *Client) review(ctx context.Context, provider Provider, pr *model.PullRequest) (*model.Review, error) {
+	budget, err := c.diffBudget(pr)
 	if err != nil {
-		return nil, fmt.Errorf("could not generate prompt: %w", err)
+		return nil, err
 	}
 
-	requestBody, err := json.Marshal(map[string]interface{}{
-		"model": c.groqModel,
-		"messages": []map[string]string{
-			{"role": "system", "content": "You are a code review assistant."},
-			{"role": "user", "content": prompt},
-		},
-		"temperature": 0.7,
-		"max_tokens":  1024,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	chunks := splitFiles(pr.Files, budget)
+	if len(chunks) <= 1 {
+		return c.reviewChunk(ctx, provider, pr)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewBuffer(requestBody))
+	reviews := make([]*model.Review, 0, len(chunks))
+	for i, chunk := range chunks {
+		chunkPR := *pr
+		chunkPR.Files = chunk
+
+		review, err := c.reviewChunk(ctx, provider, &chunkPR)
+		if err != nil {
+			return nil, fmt.Errorf("could not review chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	merged := mergeReviews(reviews)
+	merged.PullRequest = pr
+
+	if err := c.summarize(ctx, provider, pr, reviews, merged); err != nil {
+		return nil, fmt.Errorf("could not aggregate chunk reviews: %w", err)
+	}
+
+	return merged, nil
+}
+
+func (c *Client) reviewChunk(ctx context.Context, provider Provider, pr *model.PullRequest) (*model.Review, error) {
+	reviewPrompt, err := c.generatePrompt(pr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("could not generate prompt: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.groqAPIKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	response, err := c.complete(ctx, provider, reviewPrompt, reviewSchema)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request to Groq: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("groq returned non-OK status: %s, body: %s", resp.Status, string(bodyBytes))
+	review, err := c.parseResponse(response, pr)
+	if err == nil {
+		return review, nil
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode Groq response: %w", err)
+	// Give the model one chance to fix its own output.
+	repairPrompt, repairErr := c.generateRepairPrompt(response, err)
+	if repairErr != nil {
+		return nil, repairErr
 	}
+	repaired, repairErr := c.complete(ctx, provider, repairPrompt, reviewSchema)
+	if repairErr != nil {
+		return nil, fmt.Errorf("%w; asking the model to repair it failed: %v", err, repairErr)
+	}
+
+	return c.parseResponse(repaired, pr)
+}
 
-	choices, ok := result["choices"].([]interface{})
-	if !ok || len(choices) == 0 {
-		return nil, fmt.Errorf("invalid response format from Groq")
+// complete sends a single prompt to a provider and returns the raw text of
+// its answer. Providers with structured output are held to the schema; the
+// others only have the prompt to go by.
+func (c *Client) complete(ctx context.Context, provider Provider, prompt prompt, schema responseSchema) (string, error) {
+	switch provider {
+	case ProviderOllama:
+		return c.completeWithOllama(ctx, prompt, schema)
+	case ProviderGroq:
+		return c.completeWithChatCompletions(ctx, c.groq, prompt, schema)
+	case ProviderOpenAI:
+		return c.completeWithChatCompletions(ctx, c.openAI, prompt, schema)
+	case ProviderOpenAICompatible:
+		return c.completeWithChatCompletions(ctx, c.compatible, prompt, schema)
+	case ProviderAnthropic:
+		return c.completeWithAnthropic(ctx, prompt)
+	default:
+		return "", fmt.Errorf("unknown AI provider: %s", provider)
 	}
+}
 
-	message, ok := choices[0].(map[string]interface{})["message"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid message format in Groq response")
+// model returns the model that a provider is configured with.
+func (c *Client) model(provider Provider) string {
+	switch provider {
+	case ProviderOllama:
+		return c.ollamaModel
+	case ProviderGroq:
+		return c.groq.model
+	case ProviderOpenAI:
+		return c.openAI.model
+	case ProviderOpenAICompatible:
+		return c.compatible.model
+	case ProviderAnthropic:
+		return c.anthropicModel
+	default:
+		return ""
 	}
+}
 
-	content, ok := message["content"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid content in Groq response")
+func (c *Client) generatePrompt(pr *model.PullRequest) (prompt, error) {
+	p, err := executePrompt(c.reviewerTemplate, map[string]interface{}{
+		"Title":       pr.Title,
+		"Description": pr.Description,
+		"Diff":        renderDiff(pr.Files),
+	})
+	if err != nil {
+		return prompt{}, fmt.Errorf("failed to execute prompt template: %w", err)
 	}
 
-	return c.parseResponse(content, pr)
+	return p, nil
 }
 
-func (c *Client) generatePrompt(pr *model.PullRequest) (string, error) {
-	var buf bytes.Buffer
-	err := c.reviewerTemplate.Execute(&buf, pr)
+func (c *Client) generateRepairPrompt(response string, parseErr error) (prompt, error) {
+	p, err := executePrompt(c.repairTemplate, map[string]interface{}{
+		"Error":    parseErr.Error(),
+		"Response": response,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to execute prompt template: %w", err)
+		return prompt{}, fmt.Errorf("failed to execute repair prompt template: %w", err)
 	}
 
-	return buf.String(), nil
+	return p, nil
 }
 
 func (c *Client) parseResponse(response string, pr *model.PullRequest) (*model.Review, error) {
-	var reviewData struct {
-		Review model.Review `json:"review"`
+	object, err := extractJSON(response)
+	if err != nil {
+		return nil, &parseError{fmt.Errorf("failed to extract JSON from AI response: %w", err)}
 	}
 
-	err := json.Unmarshal([]byte(response), &reviewData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal AI response: %w", err)
+	// Some models leave out the "review" wrapper.
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(object), &wrapper); err != nil {
+		return nil, &parseError{fmt.Errorf("failed to unmarshal AI response: %w", err)}
+	}
+	data := []byte(object)
+	if inner, ok := wrapper["review"]; ok {
+		data = inner
+	}
+
+	var review model.Review
+	if err := json.Unmarshal(data, &review); err != nil {
+		return nil, &parseError{fmt.Errorf("failed to unmarshal AI response: %w", err)}
+	}
+	if review.Summary == "" {
+		return nil, &parseError{fmt.Errorf("AI response contains no review summary")}
 	}
 
-	reviewData.Review.PullRequest = pr
-	return &reviewData.Review, nil
+	review.PullRequest = pr
+	return &review, nil
 }
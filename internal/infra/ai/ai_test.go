@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+// TestReviewRetriesAndFallsBack sends the review to a provider that stays
+// overloaded through all retries, and checks that the fallback provider
+// reviews the pull request and that every attempt is recorded.
+func TestReviewRetriesAndFallsBack(t *testing.T) {
+	var overloaded atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		overloaded.Add(1)
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("primary got request for %s", r.URL.Path)
+		}
+		w.Header().Set("Retry-After", "0.01")
+		http.Error(w, `{"error": "overloaded"}`, http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	review := `{"summary": "Looks good.", "code_feedback": [{"file": "main.go", "line": 2, "suggestion": "Check the error."}]}`
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("fallback got request for %s", r.URL.Path)
+		}
+		line, _ := json.Marshal(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": review},
+			"done":    true,
+		})
+		_, _ = w.Write(append(line, '\n'))
+	}))
+	defer fallback.Close()
+
+	client, err := New(&config.AIConfig{
+		Provider:                 "OpenAICompatible",
+		FallbackProviders:        []string{"Ollama"},
+		MaxAttempts:              3,
+		CompatibleBaseURL:        primary.URL + "/v1",
+		CompatibleModel:          "primary-model",
+		CompatibleAuthScheme:     "none",
+		CompatibleResponseFormat: "none",
+		OllamaURL:                fallback.URL,
+		OllamaModel:              "fallback-model",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr := &model.PullRequest{
+		Title: "Add main",
+		Files: []model.File{{
+			NewPath: "main.go",
+			Status:  model.FileAdded,
+			Hunks: []model.Hunk{{
+				NewStart: 1, NewLines: 2,
+				Lines: []model.Line{
+					{Kind: model.LineAdded, Content: "package main", NewLine: 1},
+					{Kind: model.LineAdded, Content: "func main() {}", NewLine: 2},
+				},
+			}},
+		}},
+	}
+
+	got, err := client.Review(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if got.Summary != "Looks good." {
+		t.Errorf("Summary = %q, want %q", got.Summary, "Looks good.")
+	}
+	if got.Metadata.Provider != "Ollama" || got.Metadata.Model != "fallback-model" {
+		t.Errorf("reviewed by %s/%s, want Ollama/fallback-model", got.Metadata.Provider, got.Metadata.Model)
+	}
+	if n := overloaded.Load(); n != 3 {
+		t.Errorf("primary got %d requests, want 3", n)
+	}
+
+	attempts := got.Metadata.Attempts
+	if len(attempts) != 4 {
+		t.Fatalf("recorded %d attempts, want 4: %+v", len(attempts), attempts)
+	}
+	for i, attempt := range attempts[:3] {
+		if attempt.Provider != "OpenAICompatible" || attempt.Attempt != i+1 || attempt.Status != http.StatusServiceUnavailable {
+			t.Errorf("attempt %d = %+v, want OpenAICompatible with status 503", i+1, attempt)
+		}
+	}
+	if last := attempts[3]; last.Provider != "Ollama" || last.Status != http.StatusOK {
+		t.Errorf("last attempt = %+v, want Ollama with status 200", last)
+	}
+
+	if len(got.Metadata.Warnings) != 1 || !strings.Contains(got.Metadata.Warnings[0], "Ollama was used instead") {
+		t.Errorf("Warnings = %q, want one about the fallback", got.Metadata.Warnings)
+	}
+}
+
+// TestGeneratePrompt checks that the instructions go into the system part
+// and only the pull request into the user part.
+func TestGeneratePrompt(t *testing.T) {
+	client, err := New(&config.AIConfig{Provider: "Ollama"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	p, err := client.generatePrompt(&model.PullRequest{
+		Title:       "Add main",
+		Description: "Adds the entry point.",
+		Files: []model.File{{
+			NewPath: "main.go",
+			Status:  model.FileAdded,
+			Hunks: []model.Hunk{{
+				NewStart: 1, NewLines: 1,
+				Lines: []model.Line{{Kind: model.LineAdded, Content: "package main", NewLine: 1}},
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("generatePrompt() error = %v", err)
+	}
+
+	if !strings.HasPrefix(p.system, "You are PR-Reviewer") || !strings.Contains(p.system, "Guidelines for your review") {
+		t.Errorf("system part does not hold the instructions:\n%s", p.system)
+	}
+	if strings.Contains(p.system, "Add main") || strings.Contains(p.system, "package main") {
+		t.Errorf("system part holds the pull request:\n%s", p.system)
+	}
+	for _, want := range []string{"Title: 'Add main'", "Description: 'Adds the entry point.'", "## file: 'main.go' (added)", "1 +package main"} {
+		if !strings.Contains(p.user, want) {
+			t.Errorf("user part does not contain %q:\n%s", want, p.user)
+		}
+	}
+	if strings.Contains(p.user, "PR-Reviewer") {
+		t.Errorf("user part holds the instructions:\n%s", p.user)
+	}
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	defaultAnthropicVersion   = "2023-06-01"
+	defaultAnthropicMaxTokens = 4096
+)
+
+func (c *Client) completeWithAnthropic(ctx context.Context, prompt prompt) (string, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model":      c.anthropicModel,
+		"max_tokens": c.anthropicMaxTokens,
+		"system":     prompt.system,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt.user},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	resp, err := c.http.do(ctx, string(ProviderAnthropic), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.anthropicBaseURL+"/messages", bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", c.anthropicAPIKey)
+		req.Header.Set("anthropic-version", c.anthropicVersion)
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+
+	// The Messages API answers with a list of content blocks; only the text
+	// blocks carry the review, so they are joined in order.
+	var content strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("no text content in Anthropic response (stop reason: %s)", result.StopReason)
+	}
+
+	return content.String(), nil
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+func TestAnthropic(t *testing.T) {
+	p := prompt{system: "Review the pull request.", user: "The diff."}
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "text blocks",
+			response: `{"content": [
+				{"type": "thinking", "thinking": "Let me look."},
+				{"type": "text", "text": "do"},
+				{"type": "text", "text": "ne"}
+			], "stop_reason": "end_turn"}`,
+			want: "done",
+		},
+		{
+			name:     "no text",
+			response: `{"content": [], "stop_reason": "max_tokens"}`,
+			wantErr:  "stop reason: max_tokens",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/proxy/v1/messages" {
+					t.Errorf("request for %s, want /proxy/v1/messages", r.URL.Path)
+				}
+				if got := r.Header.Get("X-Api-Key"); got != "secret" {
+					t.Errorf("x-api-key = %q, want secret", got)
+				}
+				if got := r.Header.Get("Anthropic-Version"); got != defaultAnthropicVersion {
+					t.Errorf("anthropic-version = %q, want %s", got, defaultAnthropicVersion)
+				}
+
+				var body struct {
+					Model     string `json:"model"`
+					MaxTokens int    `json:"max_tokens"`
+					System    string `json:"system"`
+					Messages  []struct {
+						Role    string `json:"role"`
+						Content string `json:"content"`
+					} `json:"messages"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				if body.Model != "claude" || body.MaxTokens != defaultAnthropicMaxTokens {
+					t.Errorf("model = %q, max_tokens = %d", body.Model, body.MaxTokens)
+				}
+				if body.System != p.system {
+					t.Errorf("system = %q, want the instructions", body.System)
+				}
+				if len(body.Messages) != 1 || body.Messages[0].Role != "user" || body.Messages[0].Content != p.user {
+					t.Errorf("messages = %+v, want the diff as the only user message", body.Messages)
+				}
+
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			client, err := New(&config.AIConfig{
+				Provider:         "Anthropic",
+				MaxAttempts:      1,
+				AnthropicAPIKey:  "secret",
+				AnthropicModel:   "claude",
+				AnthropicBaseURL: srv.URL + "/proxy/v1/",
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			got, err := client.complete(context.Background(), ProviderAnthropic, p, reviewSchema)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("complete() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("complete() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("complete() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
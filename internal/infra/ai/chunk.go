@@ -0,0 +1,307 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+const (
+	// charsPerToken is a rough estimate that holds well enough for code and
+	// English prose across the tokenizers of the supported providers.
+	charsPerToken = 4
+
+	minDiffBudget = 512
+)
+
+func estimateTokens(s string) int {
+	return charsToTokens(len(s))
+}
+
+func charsToTokens(chars int) int {
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// diffBudget returns how many tokens of diff fit into a single review prompt
+// once the rest of the template has been accounted for.
+func (c *Client) diffBudget(pr *model.PullRequest) (int, error) {
+	empty := *pr
+	empty.Files = nil
+
+	prompt, err := c.generatePrompt(&empty)
+	if err != nil {
+		return 0, fmt.Errorf("could not generate prompt: %w", err)
+	}
+
+	budget := c.maxPromptTokens - prompt.tokens()
+	if budget < minDiffBudget {
+		budget = minDiffBudget
+	}
+
+	return budget, nil
+}
+
+// splitFiles splits the files of a diff into chunks whose rendered diff
+// takes at most budget tokens. Chunks are cut at file boundaries first and
+// at hunk boundaries when a single file does not fit; a hunk that is too
+// large on its own is cut between its lines.
+func splitFiles(files []model.File, budget int) [][]model.File {
+	if estimateTokens(renderDiff(files)) <= budget {
+		return [][]model.File{files}
+	}
+
+	var pieces []model.File
+	for _, file := range files {
+		if estimateTokens(renderFile(file)) <= budget {
+			pieces = append(pieces, file)
+			continue
+		}
+		pieces = append(pieces, splitFile(file, budget)...)
+	}
+
+	var chunks [][]model.File
+	var current []model.File
+	size := 0
+	for _, piece := range pieces {
+		tokens := estimateTokens(renderFile(piece))
+		if len(current) > 0 && size+tokens > budget {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, piece)
+		size += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// splitFile splits a file into several files with the same header, each
+// holding as many consecutive hunks as fit into budget.
+func splitFile(file model.File, budget int) []model.File {
+	// renderFile ends every file with a blank line.
+	headerTokens := estimateTokens(fileHeader(file) + "\n")
+
+	var hunks []model.Hunk
+	for _, hunk := range file.Hunks {
+		if headerTokens+estimateTokens(renderHunk(hunk)) <= budget {
+			hunks = append(hunks, hunk)
+			continue
+		}
+		hunks = append(hunks, splitHunk(hunk, budget-headerTokens)...)
+	}
+
+	var pieces []model.File
+	current := file
+	current.Hunks = nil
+	size := headerTokens
+	for _, hunk := range hunks {
+		tokens := estimateTokens(renderHunk(hunk))
+		if len(current.Hunks) > 0 && size+tokens > budget {
+			pieces = append(pieces, current)
+			current.Hunks, size = nil, headerTokens
+		}
+		current.Hunks = append(current.Hunks, hunk)
+		size += tokens
+	}
+	if len(current.Hunks) > 0 {
+		pieces = append(pieces, current)
+	}
+
+	return pieces
+}
+
+// splitHunk cuts a hunk between its lines into parts that each render in
+// at most budget tokens. Every line keeps its numbers and every part gets a
+// header that counts its own lines only, so the parts read like the hunks of
+// a diff with less context.
+func splitHunk(hunk model.Hunk, budget int) []model.Hunk {
+	// The header of any part is at most as long as one starting at the end
+	// of the hunk, and no line number of a part is wider than those of the
+	// hunk, so the size of a part can be bounded without rendering it.
+	width := lineNumberWidth(hunk)
+	last := hunk
+	last.OldStart += hunk.OldLines
+	last.NewStart += hunk.NewLines
+	overhead := len(hunkHeader(last)) + 1
+
+	var parts [][]model.Line
+	var current []model.Line
+	size := overhead
+	for _, line := range hunk.Lines {
+		chars := len(renderLine(line, width))
+		if len(current) > 0 && charsToTokens(size+chars) > budget {
+			parts = append(parts, current)
+			current, size = nil, overhead
+		}
+		current = append(current, line)
+		size += chars
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+
+	// The next line numbers of both sides, following the convention that a
+	// range of zero lines starts at the line before it.
+	oldNext, newNext := hunk.OldStart, hunk.NewStart
+	if hunk.OldLines == 0 {
+		oldNext++
+	}
+	if hunk.NewLines == 0 {
+		newNext++
+	}
+
+	result := make([]model.Hunk, 0, len(parts))
+	for _, lines := range parts {
+		part := model.Hunk{OldStart: oldNext, NewStart: newNext, Section: hunk.Section, Lines: lines}
+		for _, line := range lines {
+			switch line.Kind {
+			case model.LineContext:
+				part.OldLines++
+				part.NewLines++
+				oldNext, newNext = line.OldLine+1, line.NewLine+1
+			case model.LineDeleted:
+				part.OldLines++
+				oldNext = line.OldLine + 1
+			case model.LineAdded:
+				part.NewLines++
+				newNext = line.NewLine + 1
+			}
+		}
+		if part.OldLines == 0 {
+			part.OldStart--
+		}
+		if part.NewLines == 0 {
+			part.NewStart--
+		}
+		result = append(result, part)
+	}
+
+	return result
+}
+
+// mergeReviews combines the reviews of the chunks of a diff into one. The
+// summary and overall impression are only joined here; summarize replaces
+// them with a proper aggregate.
+func mergeReviews(reviews []*model.Review) *model.Review {
+	merged := &model.Review{}
+
+	var summaries, impressions, security, testing []string
+	effort := 0
+	for _, review := range reviews {
+		summaries = append(summaries, review.Summary)
+		impressions = append(impressions, review.OverallImpression)
+		security = append(security, review.SecurityConcerns)
+		testing = append(testing, review.Testing)
+
+		merged.CodeQuality.Strengths = appendUnique(merged.CodeQuality.Strengths, review.CodeQuality.Strengths...)
+		merged.CodeQuality.AreasForImprovement = appendUnique(merged.CodeQuality.AreasForImprovement, review.CodeQuality.AreasForImprovement...)
+		merged.PotentialIssues = appendUnique(merged.PotentialIssues, review.PotentialIssues...)
+		merged.Suggestions = appendUnique(merged.Suggestions, review.Suggestions...)
+		merged.CodeFeedback = append(merged.CodeFeedback, review.CodeFeedback...)
+
+		if e, err := strconv.Atoi(strings.TrimSpace(review.EstimatedEffort)); err == nil && e > effort {
+			effort = e
+		}
+	}
+
+	merged.Summary = strings.Join(appendUnique(nil, summaries...), "\n")
+	merged.OverallImpression = strings.Join(appendUnique(nil, impressions...), "\n")
+	merged.SecurityConcerns = joinSecurityConcerns(security)
+	merged.Testing = strings.Join(appendUnique(nil, testing...), "\n")
+	if effort > 0 {
+		merged.EstimatedEffort = strconv.Itoa(effort)
+	}
+
+	return merged
+}
+
+// joinSecurityConcerns drops the "None identified" answers of chunks that
+// had nothing to report, unless no chunk reported anything.
+func joinSecurityConcerns(concerns []string) string {
+	var found []string
+	for _, concern := range concerns {
+		if !strings.HasPrefix(normalize(concern), "none") {
+			found = append(found, concern)
+		}
+	}
+	found = appendUnique(nil, found...)
+	if len(found) == 0 {
+		return "None identified"
+	}
+
+	return strings.Join(found, "\n")
+}
+
+// appendUnique appends the non-empty values that are not already present in
+// dst, ignoring case and surrounding whitespace.
+func appendUnique(dst []string, values ...string) []string {
+	seen := make(map[string]bool, len(dst))
+	for _, v := range dst {
+		seen[normalize(v)] = true
+	}
+
+	for _, v := range values {
+		key := normalize(v)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		dst = append(dst, strings.TrimSpace(v))
+	}
+
+	return dst
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// summaryResponse is the answer to the summary prompt.
+type summaryResponse struct {
+	Summary           string `json:"summary"`
+	OverallImpression string `json:"overall_impression"`
+}
+
+// summarize asks the model for a single summary and overall impression of
+// the whole pull request based on the partial reviews of its chunks.
+func (c *Client) summarize(ctx context.Context, provider Provider, pr *model.PullRequest, reviews []*model.Review, merged *model.Review) error {
+	summaryPrompt, err := executePrompt(c.summaryTemplate, map[string]interface{}{
+		"Title":       pr.Title,
+		"Description": pr.Description,
+		"Reviews":     reviews,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute summary prompt template: %w", err)
+	}
+
+	response, err := c.complete(ctx, provider, summaryPrompt, summarySchema)
+	if err != nil {
+		return err
+	}
+
+	object, err := extractJSON(response)
+	if err != nil {
+		return &parseError{fmt.Errorf("failed to extract JSON from AI summary response: %w", err)}
+	}
+
+	var summary summaryResponse
+	if err := json.Unmarshal([]byte(object), &summary); err != nil {
+		return &parseError{fmt.Errorf("failed to unmarshal AI summary response: %w", err)}
+	}
+
+	if summary.Summary != "" {
+		merged.Summary = summary.Summary
+	}
+	if summary.OverallImpression != "" {
+		merged.OverallImpression = summary.OverallImpression
+	}
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestSplitHunk(t *testing.T) {
+	tests := []struct {
+		name   string
+		hunk   model.Hunk
+		budget int
+		want   []string
+	}{
+		{
+			name: "fits",
+			hunk: model.Hunk{
+				OldStart: 10, OldLines: 2, NewStart: 10, NewLines: 2,
+				Lines: []model.Line{
+					{Kind: model.LineContext, Content: "aaaa", OldLine: 10, NewLine: 10},
+					{Kind: model.LineDeleted, Content: "aaaa", OldLine: 11},
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 11},
+				},
+			},
+			budget: 100,
+			want:   []string{"@@ -10,2 +10,2 @@"},
+		},
+		{
+			// Each part takes a 19 character header and two lines of 9
+			// characters: 37 characters or 10 tokens.
+			name: "mixed lines",
+			hunk: model.Hunk{
+				OldStart: 10, OldLines: 4, NewStart: 10, NewLines: 4,
+				Lines: []model.Line{
+					{Kind: model.LineContext, Content: "aaaa", OldLine: 10, NewLine: 10},
+					{Kind: model.LineDeleted, Content: "aaaa", OldLine: 11},
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 11},
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 12},
+					{Kind: model.LineContext, Content: "aaaa", OldLine: 12, NewLine: 13},
+					{Kind: model.LineDeleted, Content: "aaaa", OldLine: 13},
+				},
+			},
+			budget: 10,
+			want:   []string{"@@ -10,2 +10,1 @@", "@@ -11,0 +11,2 @@", "@@ -12,2 +13,1 @@"},
+		},
+		{
+			name: "added file",
+			hunk: model.Hunk{
+				OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 3,
+				Lines: []model.Line{
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 1},
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 2},
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 3},
+				},
+			},
+			budget: 9,
+			want:   []string{"@@ -0,0 +1,2 @@", "@@ -0,0 +3,1 @@"},
+		},
+		{
+			name: "line larger than the budget",
+			hunk: model.Hunk{
+				OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 2,
+				Lines: []model.Line{
+					{Kind: model.LineContext, Content: strings.Repeat("a", 100), OldLine: 1, NewLine: 1},
+					{Kind: model.LineAdded, Content: "aaaa", NewLine: 2},
+				},
+			},
+			budget: 10,
+			want:   []string{"@@ -1,1 +1,1 @@", "@@ -1,0 +2,1 @@"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitHunk(tt.hunk, tt.budget)
+
+			var got []string
+			lines := 0
+			for _, part := range parts {
+				got = append(got, strings.TrimSuffix(hunkHeader(part), "\n"))
+				lines += len(part.Lines)
+				if len(part.Lines) > 1 && estimateTokens(renderHunk(part)) > tt.budget {
+					t.Errorf("part %s takes %d tokens, more than the budget of %d", got[len(got)-1], estimateTokens(renderHunk(part)), tt.budget)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitHunk() headers = %q, want %q", got, tt.want)
+			}
+			if lines != len(tt.hunk.Lines) {
+				t.Errorf("splitHunk() kept %d of %d lines", lines, len(tt.hunk.Lines))
+			}
+		})
+	}
+}
+
+// addedFile returns an added file with the given number of hunks and lines
+// per hunk.
+func addedFile(path string, hunks, lines int) model.File {
+	file := model.File{NewPath: path, Status: model.FileAdded}
+	for h := 0; h < hunks; h++ {
+		hunk := model.Hunk{OldStart: 0, NewStart: h*100 + 1, NewLines: lines}
+		for i := 0; i < lines; i++ {
+			hunk.Lines = append(hunk.Lines, model.Line{
+				Kind:    model.LineAdded,
+				Content: fmt.Sprintf("line %d of hunk %d in %s", i+1, h+1, path),
+				NewLine: hunk.NewStart + i,
+			})
+		}
+		file.Hunks = append(file.Hunks, hunk)
+	}
+	return file
+}
+
+func TestSplitFiles(t *testing.T) {
+	small, medium, large := addedFile("a.go", 1, 10), addedFile("b.go", 2, 10), addedFile("c.go", 6, 10)
+	huge := addedFile("d.go", 1, 40)
+
+	tests := []struct {
+		name   string
+		files  []model.File
+		budget int
+		// want describes every chunk as its files and their hunk headers.
+		want [][]string
+	}{
+		{
+			name:   "everything fits",
+			files:  []model.File{small, medium},
+			budget: 1000,
+			want:   [][]string{{"a.go @@ -0,0 +1,10 @@", "b.go @@ -0,0 +1,10 @@ @@ -0,0 +101,10 @@"}},
+		},
+		{
+			name:   "cut between files",
+			files:  []model.File{small, medium, small},
+			budget: 200,
+			want: [][]string{
+				{"a.go @@ -0,0 +1,10 @@"},
+				{"b.go @@ -0,0 +1,10 @@ @@ -0,0 +101,10 @@"},
+				{"a.go @@ -0,0 +1,10 @@"},
+			},
+		},
+		{
+			name:   "cut between hunks",
+			files:  []model.File{large},
+			budget: 200,
+			want: [][]string{
+				{"c.go @@ -0,0 +1,10 @@ @@ -0,0 +101,10 @@"},
+				{"c.go @@ -0,0 +201,10 @@ @@ -0,0 +301,10 @@"},
+				{"c.go @@ -0,0 +401,10 @@ @@ -0,0 +501,10 @@"},
+			},
+		},
+		{
+			name:   "cut within a hunk",
+			files:  []model.File{huge},
+			budget: 200,
+			want: [][]string{
+				{"d.go @@ -0,0 +1,25 @@"},
+				{"d.go @@ -0,0 +26,15 @@"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitFiles(tt.files, tt.budget)
+
+			var got [][]string
+			for _, chunk := range chunks {
+				if tokens := estimateTokens(renderDiff(chunk)); tokens > tt.budget {
+					t.Errorf("chunk takes %d tokens, more than the budget of %d", tokens, tt.budget)
+				}
+
+				var files []string
+				for _, file := range chunk {
+					desc := file.Path()
+					for _, hunk := range file.Hunks {
+						desc += " " + strings.TrimSuffix(hunkHeader(hunk), "\n")
+					}
+					files = append(files, desc)
+				}
+				got = append(got, files)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitFiles() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeReviews(t *testing.T) {
+	line := 3
+	reviews := []*model.Review{
+		{
+			Summary:           "Adds the parser.",
+			OverallImpression: "Solid.",
+			CodeQuality: model.CodeQuality{
+				Strengths: []string{"Small functions"},
+			},
+			PotentialIssues:  []string{"Unchecked error"},
+			Suggestions:      []string{"Add tests"},
+			SecurityConcerns: "None identified",
+			Testing:          "No tests.",
+			EstimatedEffort:  "2",
+			CodeFeedback:     []model.Feedback{{File: "parse.go", Line: &line, Suggestion: "Check err."}},
+		},
+		{
+			Summary:           "Adds the printer.",
+			OverallImpression: "solid. ",
+			CodeQuality: model.CodeQuality{
+				Strengths:           []string{"small  functions"},
+				AreasForImprovement: []string{"Naming"},
+			},
+			PotentialIssues:  []string{"Unchecked error", "Race on close"},
+			SecurityConcerns: "Logs the token.",
+			Testing:          "No tests.",
+			EstimatedEffort:  " 4 ",
+			CodeFeedback:     []model.Feedback{{File: "print.go", Suggestion: "Split the file."}},
+		},
+	}
+
+	want := &model.Review{
+		Summary:           "Adds the parser.\nAdds the printer.",
+		OverallImpression: "Solid.",
+		CodeQuality: model.CodeQuality{
+			Strengths:           []string{"Small functions"},
+			AreasForImprovement: []string{"Naming"},
+		},
+		PotentialIssues:  []string{"Unchecked error", "Race on close"},
+		Suggestions:      []string{"Add tests"},
+		SecurityConcerns: "Logs the token.",
+		Testing:          "No tests.",
+		EstimatedEffort:  "4",
+		CodeFeedback: []model.Feedback{
+			{File: "parse.go", Line: &line, Suggestion: "Check err."},
+			{File: "print.go", Suggestion: "Split the file."},
+		},
+	}
+
+	if got := mergeReviews(reviews); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeReviews() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestJoinSecurityConcerns(t *testing.T) {
+	tests := []struct {
+		concerns []string
+		want     string
+	}{
+		{[]string{"None identified", "none."}, "None identified"},
+		{[]string{"None identified", "SQL injection in query.go", "sql injection in query.go"}, "SQL injection in query.go"},
+		{[]string{"Leaks the token.", "", "Disables TLS verification."}, "Leaks the token.\nDisables TLS verification."},
+	}
+
+	for _, tt := range tests {
+		if got := joinSecurityConcerns(tt.concerns); got != tt.want {
+			t.Errorf("joinSecurityConcerns(%q) = %q, want %q", tt.concerns, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name           string
+		answer         string
+		wantSummary    string
+		wantImpression string
+		wantErr        bool
+	}{
+		{
+			name:           "replaces both",
+			answer:         `{"summary": "Adds parsing and printing.", "overall_impression": "Good overall."}`,
+			wantSummary:    "Adds parsing and printing.",
+			wantImpression: "Good overall.",
+		},
+		{
+			name:           "keeps what is missing",
+			answer:         "Here you go: {\"summary\": \"Adds parsing and printing.\"}",
+			wantSummary:    "Adds parsing and printing.",
+			wantImpression: "Solid.",
+		},
+		{
+			name:    "unparsable",
+			answer:  "I cannot summarize this.",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Messages []struct {
+						Role    string `json:"role"`
+						Content string `json:"content"`
+					} `json:"messages"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Messages) != 2 {
+					t.Errorf("request = %+v, %v, want a system and a user message", body, err)
+					return
+				}
+				if system := body.Messages[0].Content; !strings.Contains(system, "split into 2 parts") {
+					t.Errorf("system message = %q, want the number of parts", system)
+				}
+				for _, want := range []string{"Title: 'Parse and print'", "Summary: Adds the parser.", "Summary: Adds the printer."} {
+					if user := body.Messages[1].Content; !strings.Contains(user, want) {
+						t.Errorf("user message does not contain %q", want)
+					}
+				}
+
+				answer, _ := json.Marshal(tt.answer)
+				fmt.Fprintf(w, `{"choices": [{"message": {"content": %s}}]}`, answer)
+			}))
+			defer srv.Close()
+
+			client, err := New(&config.AIConfig{
+				Provider:             "OpenAICompatible",
+				MaxAttempts:          1,
+				CompatibleBaseURL:    srv.URL,
+				CompatibleAuthScheme: "none",
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			reviews := []*model.Review{
+				{Summary: "Adds the parser.", OverallImpression: "Solid."},
+				{Summary: "Adds the printer.", OverallImpression: "Solid."},
+			}
+			merged := mergeReviews(reviews)
+			err = client.summarize(context.Background(), ProviderOpenAICompatible, &model.PullRequest{Title: "Parse and print"}, reviews, merged)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("summarize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if merged.Summary != tt.wantSummary || merged.OverallImpression != tt.wantImpression {
+				t.Errorf("summarize() = %q / %q, want %q / %q", merged.Summary, merged.OverallImpression, tt.wantSummary, tt.wantImpression)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+// renderDiff renders files in the format described by the reviewer prompt.
+func renderDiff(files []model.File) string {
+	var b strings.Builder
+	for _, file := range files {
+		b.WriteString(renderFile(file))
+	}
+
+	return b.String()
+}
+
+func renderFile(file model.File) string {
+	var b strings.Builder
+	b.WriteString(fileHeader(file))
+	for _, hunk := range file.Hunks {
+		b.WriteString(renderHunk(hunk))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func fileHeader(file model.File) string {
+	header := fmt.Sprintf("## file: '%s'", file.Path())
+	switch file.Status {
+	case model.FileAdded, model.FileDeleted:
+		header += fmt.Sprintf(" (%s)", file.Status)
+	case model.FileRenamed:
+		header += fmt.Sprintf(" (renamed from '%s')", file.OldPath)
+	case model.FileBinary:
+		header += " (binary, content not shown)"
+	}
+
+	return header + "\n\n"
+}
+
+// renderHunk prefixes every line with its number in the new version of the
+// file. Deleted lines have no such number and get blanks instead.
+func renderHunk(hunk model.Hunk) string {
+	width := lineNumberWidth(hunk)
+
+	var b strings.Builder
+	b.WriteString(hunkHeader(hunk))
+	for _, line := range hunk.Lines {
+		b.WriteString(renderLine(line, width))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func hunkHeader(hunk model.Hunk) string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	if hunk.Section != "" {
+		header += " " + hunk.Section
+	}
+
+	return header + "\n"
+}
+
+// lineNumberWidth is the width of the largest line number of the hunk.
+func lineNumberWidth(hunk model.Hunk) int {
+	return len(strconv.Itoa(hunk.NewStart + hunk.NewLines))
+}
+
+func renderLine(line model.Line, width int) string {
+	switch line.Kind {
+	case model.LineNoNewline:
+		return fmt.Sprintf("%*s \\ %s\n", width, "", line.Content)
+	case model.LineDeleted:
+		return fmt.Sprintf("%*s %s%s\n", width, "", line.Kind, line.Content)
+	default:
+		return fmt.Sprintf("%*d %s%s\n", width, line.NewLine, line.Kind, line.Content)
+	}
+}
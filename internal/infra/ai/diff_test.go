@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestRenderDiff(t *testing.T) {
+	files := []model.File{
+		{
+			OldPath: "main.go",
+			NewPath: "main.go",
+			Status:  model.FileModified,
+			Hunks: []model.Hunk{{
+				OldStart: 8, OldLines: 3, NewStart: 8, NewLines: 3,
+				Section: "func main() {",
+				Lines: []model.Line{
+					{Kind: model.LineContext, Content: "\tx := 1", OldLine: 8, NewLine: 8},
+					{Kind: model.LineDeleted, Content: "\tfmt.Println(x)", OldLine: 9},
+					{Kind: model.LineAdded, Content: "\tlog.Println(x)", NewLine: 9},
+					{Kind: model.LineContext, Content: "}", OldLine: 10, NewLine: 10},
+				},
+			}},
+		},
+		{
+			NewPath: "doc.go",
+			Status:  model.FileAdded,
+			Hunks: []model.Hunk{{
+				OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1,
+				Lines: []model.Line{
+					{Kind: model.LineAdded, Content: "package main", NewLine: 1},
+					{Kind: model.LineNoNewline, Content: "No newline at end of file"},
+				},
+			}},
+		},
+		{OldPath: "old.go", NewPath: "new.go", Status: model.FileRenamed},
+		{OldPath: "gone.go", Status: model.FileDeleted},
+		{OldPath: "logo.png", NewPath: "logo.png", Status: model.FileBinary},
+	}
+
+	want := `## file: 'main.go'
+
+@@ -8,3 +8,3 @@ func main() {
+ 8  	x := 1
+   -	fmt.Println(x)
+ 9 +	log.Println(x)
+10  }
+
+
+## file: 'doc.go' (added)
+
+@@ -0,0 +1,1 @@
+1 +package main
+  \ No newline at end of file
+
+
+## file: 'new.go' (renamed from 'old.go')
+
+
+## file: 'gone.go' (deleted)
+
+
+## file: 'logo.png' (binary, content not shown)
+
+
+`
+	if got := renderDiff(files); got != want {
+		t.Errorf("renderDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// extractJSON returns the outermost JSON object of a model response. Models
+// like to wrap their answer in code fences or surround it with prose, and
+// some leave trailing commas behind, which are dropped.
+func extractJSON(response string) (string, error) {
+	for start := strings.IndexByte(response, '{'); start >= 0; {
+		if end := matchBrace(response, start); end > start {
+			candidate := removeTrailingCommas(response[start : end+1])
+			if json.Valid([]byte(candidate)) {
+				return candidate, nil
+			}
+		}
+
+		next := strings.IndexByte(response[start+1:], '{')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+
+	return "", errors.New("no JSON object found")
+}
+
+// matchBrace returns the index of the brace that closes the one at start,
+// skipping braces inside strings, or -1 when it is never closed.
+func matchBrace(s string, start int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// removeTrailingCommas drops commas that directly precede a closing brace or
+// bracket outside of strings.
+func removeTrailingCommas(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case !inString && ch == ',':
+			rest := strings.TrimLeft(s[i+1:], " \t\r\n")
+			if rest != "" && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+		}
+		b.WriteByte(ch)
+	}
+
+	return b.String()
+}
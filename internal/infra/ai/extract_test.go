@@ -0,0 +1,65 @@
+package ai
+
+import "testing"
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "bare object",
+			response: `{"summary": "ok"}`,
+			want:     `{"summary": "ok"}`,
+		},
+		{
+			name:     "code fence",
+			response: "```json\n{\"summary\": \"ok\"}\n```",
+			want:     `{"summary": "ok"}`,
+		},
+		{
+			name:     "surrounded by prose",
+			response: "Here is the review:\n{\"summary\": \"ok\"}\nLet me know if you need more.",
+			want:     `{"summary": "ok"}`,
+		},
+		{
+			name:     "braces in strings",
+			response: `{"summary": "use } and { carefully", "suggestions": ["a \"{\" b"]}`,
+			want:     `{"summary": "use } and { carefully", "suggestions": ["a \"{\" b"]}`,
+		},
+		{
+			name:     "trailing commas",
+			response: "{\"suggestions\": [\"a\", \"b\",],\n \"summary\": \"ok, fine\",\n}",
+			want:     "{\"suggestions\": [\"a\", \"b\"],\n \"summary\": \"ok, fine\"\n}",
+		},
+		{
+			name:     "prose with braces before the object",
+			response: `Reviewing {the diff} now: {"summary": "ok"}`,
+			want:     `{"summary": "ok"}`,
+		},
+		{
+			name:     "unterminated",
+			response: `{"summary": "ok"`,
+			wantErr:  true,
+		},
+		{
+			name:     "no object",
+			response: "I cannot review this change.",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractJSON(tt.response)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
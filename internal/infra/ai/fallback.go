@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseError is returned when the output of a model cannot be parsed, even
+// after it was asked to repair it.
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string { return e.err.Error() }
+
+func (e *parseError) Unwrap() error { return e.err }
+
+// contextLengthHints are phrases with which providers reject prompts that
+// exceed the context of the model.
+var contextLengthHints = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"maximum context",
+	"prompt is too long",
+	"too many tokens",
+	"reduce the length",
+}
+
+// canFallBack reports whether another provider may succeed where one failed
+// with err: when the provider could not be reached, was rate limited or
+// overloaded, could not fit the prompt into its context or answered with
+// output that could not be parsed. Other errors, such as a rejected API
+// key, are left for the user to fix.
+func canFallBack(err error) bool {
+	var pe *parseError
+	if errors.As(err, &pe) || errors.Is(err, errCircuitOpen) {
+		return true
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return retryableStatus(se.statusCode) || isContextLengthError(se)
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+func isContextLengthError(se *statusError) bool {
+	if se.statusCode == http.StatusRequestEntityTooLarge {
+		return true
+	}
+	if se.statusCode != http.StatusBadRequest {
+		return false
+	}
+
+	body := strings.ToLower(se.body)
+	for _, hint := range contextLengthHints {
+		if strings.Contains(body, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+const (
+	defaultTimeout          = 300 * time.Second
+	defaultMaxAttempts      = 4
+	defaultMaxRetryWait     = 60 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 60 * time.Second
+	retryBaseDelay          = time.Second
+)
+
+// errCircuitOpen is returned without sending a request while the circuit
+// breaker of a provider is open.
+var errCircuitOpen = errors.New("circuit breaker is open after repeated failures")
+
+// statusError is a response with a status code outside of 2xx.
+type statusError struct {
+	provider   string
+	status     string
+	statusCode int
+	body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned non-OK status: %s, body: %s", e.provider, e.status, e.body)
+}
+
+// httpClient is the HTTP layer shared by the providers. Requests that fail
+// with a transport error, a rate limit or a server error are retried with
+// exponential backoff and jitter, waiting as long as the provider asks for
+// through Retry-After or its rate limit headers. A circuit breaker per
+// provider fails requests fast once several of them in a row failed despite
+// their retries.
+type httpClient struct {
+	client           *http.Client
+	maxAttempts      int
+	maxRetryWait     time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newHTTPClient(cfg *config.AIConfig) *httpClient {
+	h := &httpClient{
+		client:           &http.Client{Timeout: seconds(cfg.TimeoutSeconds, defaultTimeout)},
+		maxAttempts:      cfg.MaxAttempts,
+		maxRetryWait:     seconds(cfg.MaxRetryWaitSeconds, defaultMaxRetryWait),
+		breakerThreshold: cfg.CircuitBreakerThreshold,
+		breakerCooldown:  seconds(cfg.CircuitBreakerCooldownSeconds, defaultBreakerCooldown),
+		breakers:         make(map[string]*breaker),
+	}
+	if h.maxAttempts <= 0 {
+		h.maxAttempts = defaultMaxAttempts
+	}
+	if h.breakerThreshold <= 0 {
+		h.breakerThreshold = defaultBreakerThreshold
+	}
+	return h
+}
+
+func seconds(value int, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+// do sends the request built by newRequest, which is called again for every
+// attempt since a request body can only be read once. It returns the first
+// response with a 2xx status; the caller closes its body. Every attempt is
+// recorded with the recorder of ctx.
+func (h *httpClient) do(ctx context.Context, provider string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := h.allow(provider); err != nil {
+			recordAttempt(ctx, model.Attempt{Provider: provider, Attempt: attempt, Error: err.Error()})
+			return nil, fmt.Errorf("%s: %w", provider, err)
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		start := time.Now()
+		resp, err := h.client.Do(req)
+		record := model.Attempt{Provider: provider, Attempt: attempt, DurationMS: time.Since(start).Milliseconds()}
+
+		var wait time.Duration
+		var retryable bool
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to send request to %s: %w", provider, err)
+			}
+			err = fmt.Errorf("failed to send request to %s: %w", provider, err)
+			retryable = true
+		case resp.StatusCode < 200 || resp.StatusCode > 299:
+			record.Status = resp.StatusCode
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			err = &statusError{provider: provider, status: resp.Status, statusCode: resp.StatusCode, body: string(body)}
+			retryable = retryableStatus(resp.StatusCode)
+			wait = retryAfter(resp.Header, time.Now())
+		default:
+			record.Status = resp.StatusCode
+			recordAttempt(ctx, record)
+			h.succeeded(provider)
+			return resp, nil
+		}
+
+		record.Error = err.Error()
+		recordAttempt(ctx, record)
+		if !retryable {
+			return nil, err
+		}
+		if attempt >= h.maxAttempts {
+			h.failed(provider)
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		if wait > h.maxRetryWait {
+			h.failed(provider)
+			return nil, fmt.Errorf("%s asked to wait %s, longer than the configured maximum of %s: %w", provider, wait, h.maxRetryWait, err)
+		}
+		if wait == 0 {
+			wait = min(backoff(attempt), h.maxRetryWait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// allow fails while the breaker of the provider is open. Once the cooldown
+// has passed, requests are let through again and the next failure opens the
+// breaker right away.
+func (h *httpClient) allow(provider string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b := h.breakers[provider]
+	if b != nil && time.Now().Before(b.openUntil) {
+		return errCircuitOpen
+	}
+	return nil
+}
+
+func (h *httpClient) succeeded(provider string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.breakers, provider)
+}
+
+func (h *httpClient) failed(provider string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b := h.breakers[provider]
+	if b == nil {
+		b = &breaker{}
+		h.breakers[provider] = b
+	}
+	b.failures++
+	if b.failures >= h.breakerThreshold {
+		b.openUntil = time.Now().Add(h.breakerCooldown)
+	}
+}
+
+// retryableStatus reports whether a status code is worth retrying. 529 is
+// what Anthropic answers when it is overloaded.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests, 529:
+		return true
+	default:
+		return code >= 500
+	}
+}
+
+// backoff returns an exponentially growing delay with jitter for the retry
+// after the given attempt.
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << min(attempt-1, 16)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfter returns how long the provider asked to wait, or zero. It reads
+// Retry-After, in seconds or as a date, and otherwise the reset headers of
+// exhausted rate limits: x-ratelimit-reset-* as sent by OpenAI and Groq,
+// and anthropic-ratelimit-*-reset.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if secs, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if date, err := http.ParseTime(value); err == nil && date.After(now) {
+			return date.Sub(now)
+		}
+	}
+
+	var wait time.Duration
+	for name := range header {
+		lower := strings.ToLower(name)
+		var reset time.Duration
+		switch {
+		case strings.HasPrefix(lower, "x-ratelimit-reset-"):
+			limit := strings.TrimPrefix(lower, "x-ratelimit-reset-")
+			if header.Get("x-ratelimit-remaining-"+limit) != "0" {
+				continue
+			}
+			reset, _ = time.ParseDuration(header.Get(name))
+		case strings.HasPrefix(lower, "anthropic-ratelimit-") && strings.HasSuffix(lower, "-reset"):
+			limit := strings.TrimSuffix(lower, "-reset")
+			if header.Get(limit+"-remaining") != "0" {
+				continue
+			}
+			if date, err := time.Parse(time.RFC3339, header.Get(name)); err == nil {
+				reset = date.Sub(now)
+			}
+		}
+		if reset > wait {
+			wait = reset
+		}
+	}
+
+	return wait
+}
+
+type recorderKey struct{}
+
+// recorder collects the attempts of the requests made for one review and
+// the warnings the providers raised along the way.
+type recorder struct {
+	mu       sync.Mutex
+	attempts []model.Attempt
+	warnings []string
+}
+
+func withRecorder(ctx context.Context) (context.Context, *recorder) {
+	r := &recorder{}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+func recordAttempt(ctx context.Context, attempt model.Attempt) {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, attempt)
+}
+
+func recordWarning(ctx context.Context, warning string) {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, warning)
+}
+
+func (r *recorder) list() []model.Attempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.Attempt(nil), r.attempts...)
+}
+
+func (r *recorder) listWarnings() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.warnings...)
+}
@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{
+			name: "none",
+			want: 0,
+		},
+		{
+			name:   "seconds",
+			header: http.Header{"Retry-After": {"7"}},
+			want:   7 * time.Second,
+		},
+		{
+			name:   "fractional seconds",
+			header: http.Header{"Retry-After": {"0.5"}},
+			want:   500 * time.Millisecond,
+		},
+		{
+			name:   "date",
+			header: http.Header{"Retry-After": {now.Add(30 * time.Second).Format(http.TimeFormat)}},
+			want:   30 * time.Second,
+		},
+		{
+			name:   "date in the past",
+			header: http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}},
+			want:   0,
+		},
+		{
+			name: "exhausted OpenAI limit",
+			header: http.Header{
+				"X-Ratelimit-Remaining-Requests": {"0"},
+				"X-Ratelimit-Reset-Requests":     {"1m30s"},
+				"X-Ratelimit-Remaining-Tokens":   {"1200"},
+				"X-Ratelimit-Reset-Tokens":       {"5m"},
+			},
+			want: 90 * time.Second,
+		},
+		{
+			name: "longest exhausted limit",
+			header: http.Header{
+				"X-Ratelimit-Remaining-Requests": {"0"},
+				"X-Ratelimit-Reset-Requests":     {"2s"},
+				"X-Ratelimit-Remaining-Tokens":   {"0"},
+				"X-Ratelimit-Reset-Tokens":       {"6.5s"},
+			},
+			want: 6500 * time.Millisecond,
+		},
+		{
+			name: "exhausted Anthropic limit",
+			header: http.Header{
+				"Anthropic-Ratelimit-Tokens-Remaining": {"0"},
+				"Anthropic-Ratelimit-Tokens-Reset":     {now.Add(20 * time.Second).Format(time.RFC3339)},
+			},
+			want: 20 * time.Second,
+		},
+		{
+			name: "Retry-After wins over reset headers",
+			header: http.Header{
+				"Retry-After":                    {"3"},
+				"X-Ratelimit-Remaining-Requests": {"0"},
+				"X-Ratelimit-Reset-Requests":     {"1m"},
+			},
+			want: 3 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.header, now); got != tt.want {
+				t.Errorf("retryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 20; attempt++ {
+		delay := retryBaseDelay << min(attempt-1, 16)
+		for i := 0; i < 100; i++ {
+			if got := backoff(attempt); got < delay/2 || got > delay {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, got, delay/2, delay)
+			}
+		}
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusNotFound:            false,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+		529:                            true,
+	} {
+		if got := retryableStatus(code); got != want {
+			t.Errorf("retryableStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	h := newHTTPClient(&config.AIConfig{MaxAttempts: 3})
+	ctx, rec := withRecorder(context.Background())
+	resp, err := h.do(ctx, "Test", func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	attempts := rec.list()
+	if len(attempts) != 3 {
+		t.Fatalf("recorded %d attempts, want 3: %+v", len(attempts), attempts)
+	}
+	for i, want := range []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusOK} {
+		if attempts[i].Attempt != i+1 || attempts[i].Status != want {
+			t.Errorf("attempt %d = %+v, want status %d", i+1, attempts[i], want)
+		}
+	}
+}
+
+func TestHTTPClientDoesNotRetryClientErrors(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	h := newHTTPClient(&config.AIConfig{MaxAttempts: 3})
+	_, err := h.do(context.Background(), "Test", func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, srv.URL, nil)
+	})
+
+	var se *statusError
+	if !errors.As(err, &se) || se.statusCode != http.StatusUnauthorized {
+		t.Fatalf("do() error = %v, want a 401 status error", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestHTTPClientCircuitBreaker(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	h := newHTTPClient(&config.AIConfig{MaxAttempts: 1, CircuitBreakerThreshold: 2, CircuitBreakerCooldownSeconds: 60})
+	send := func() error {
+		_, err := h.do(context.Background(), "Test", func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, srv.URL, nil)
+		})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := send(); err == nil || errors.Is(err, errCircuitOpen) {
+			t.Fatalf("request %d: error = %v, want a status error", i+1, err)
+		}
+	}
+	if err := send(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("error = %v, want errCircuitOpen", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultOllamaURL   = "http://localhost:11434"
+	ollamaChatPath     = "/api/chat"
+	ollamaGeneratePath = "/api/generate"
+
+	// ollamaMinContext is the smallest context num_ctx is sized to, and
+	// ollamaAnswerTokens the room left in it for the answer.
+	ollamaMinContext   = 2048
+	ollamaAnswerTokens = 2048
+)
+
+// ollamaEndpoint resolves the configured Ollama URL. URLs ending in
+// /api/generate or /api/chat are used as they are; anything else is taken
+// as the address of the server, whose chat endpoint is used. An empty URL
+// is the local server.
+func ollamaEndpoint(rawURL string) (string, bool) {
+	if rawURL == "" {
+		rawURL = defaultOllamaURL
+	}
+	url := strings.TrimSuffix(rawURL, "/")
+	switch {
+	case strings.HasSuffix(url, ollamaGeneratePath):
+		return url, false
+	case strings.HasSuffix(url, ollamaChatPath):
+		return url, true
+	default:
+		return url + ollamaChatPath, true
+	}
+}
+
+// ollamaKeepAlive passes numbers on as seconds, since Ollama only reads
+// strings as durations like "10m".
+func ollamaKeepAlive(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return seconds
+	}
+	return value
+}
+
+// ollamaNumCtx sizes the context to fit the prompt and the answer, since
+// Ollama silently truncates prompts to its default context of a few
+// thousand tokens.
+func ollamaNumCtx(promptTokens int) int {
+	numCtx := promptTokens + ollamaAnswerTokens
+	numCtx = (numCtx + 1023) / 1024 * 1024
+	if numCtx < ollamaMinContext {
+		numCtx = ollamaMinContext
+	}
+	return numCtx
+}
+
+func (c *Client) completeWithOllama(ctx context.Context, prompt prompt, schema responseSchema) (string, error) {
+	promptTokens := prompt.tokens()
+
+	// Configured options take precedence, including num_ctx.
+	options := map[string]interface{}{"num_ctx": ollamaNumCtx(promptTokens)}
+	for key, value := range c.ollamaOptions {
+		options[key] = value
+	}
+
+	body := map[string]interface{}{
+		"model":   c.ollamaModel,
+		"options": options,
+	}
+	if c.ollamaChat {
+		body["messages"] = []map[string]string{
+			{"role": "system", "content": prompt.system},
+			{"role": "user", "content": prompt.user},
+		}
+	} else {
+		body["system"] = prompt.system
+		body["prompt"] = prompt.user
+	}
+	if keepAlive := ollamaKeepAlive(c.ollamaKeepAlive); keepAlive != nil {
+		body["keep_alive"] = keepAlive
+	}
+	switch c.ollamaFormat {
+	case ResponseFormatJSONSchema:
+		body["format"] = schema.schema
+	case ResponseFormatJSONObject:
+		body["format"] = "json"
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	resp, err := c.http.do(ctx, string(ProviderOllama), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.ollamaURL, bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Both endpoints stream one JSON object per line; /api/chat puts the
+	// text into message.content and /api/generate into response.
+	var fullResponse strings.Builder
+	promptEvalCount := 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var result struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Response        string `json:"response"`
+			Done            bool   `json:"done"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			Error           string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return "", fmt.Errorf("failed to decode Ollama response: %w", err)
+		}
+		if result.Error != "" {
+			return "", fmt.Errorf("ollama returned an error: %s", result.Error)
+		}
+		fullResponse.WriteString(result.Message.Content)
+		fullResponse.WriteString(result.Response)
+		if result.Done {
+			promptEvalCount = result.PromptEvalCount
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading Ollama response: %w", err)
+	}
+
+	// The estimate is rough and Ollama does not count prompt tokens it
+	// reuses from its cache, so only a large gap points at truncation.
+	if promptEvalCount > 0 && promptEvalCount < promptTokens/2 {
+		recordWarning(ctx, fmt.Sprintf(
+			"Ollama evaluated only %d of about %d prompt tokens, so the prompt was probably truncated; raise num_ctx in ollama_options or lower max_prompt_tokens",
+			promptEvalCount, promptTokens,
+		))
+	}
+
+	return fullResponse.String(), nil
+}
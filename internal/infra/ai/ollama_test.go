@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+// ollamaRequest is what the tests read from requests to both endpoints.
+type ollamaRequest struct {
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	System  string                 `json:"system"`
+	Prompt  string                 `json:"prompt"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// newOllamaServer answers every request with the given lines and records
+// the path and body of the last request.
+func newOllamaServer(t *testing.T, lines ...string) (*httptest.Server, *string, *ollamaRequest) {
+	t.Helper()
+	var path string
+	var body ollamaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body = ollamaRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		for _, line := range lines {
+			_, _ = w.Write([]byte(line + "\n"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &path, &body
+}
+
+func TestOllamaEndpoint(t *testing.T) {
+	p := prompt{system: "Review the pull request.", user: "The diff."}
+
+	tests := []struct {
+		name     string
+		suffix   string
+		wantPath string
+		lines    []string
+	}{
+		{
+			name:     "server address",
+			suffix:   "/",
+			wantPath: "/api/chat",
+			lines:    []string{`{"message": {"content": "do"}}`, `{"message": {"content": "ne"}, "done": true}`},
+		},
+		{
+			name:     "chat endpoint",
+			suffix:   "/api/chat",
+			wantPath: "/api/chat",
+			lines:    []string{`{"message": {"content": "done"}, "done": true}`},
+		},
+		{
+			name:     "legacy generate endpoint",
+			suffix:   "/api/generate",
+			wantPath: "/api/generate",
+			lines:    []string{`{"response": "do"}`, `{"response": "ne", "done": true}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, path, body := newOllamaServer(t, tt.lines...)
+			client, err := New(&config.AIConfig{Provider: "Ollama", OllamaURL: srv.URL + tt.suffix, OllamaModel: "llama3", MaxAttempts: 1})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			got, err := client.complete(context.Background(), ProviderOllama, p, reviewSchema)
+			if err != nil {
+				t.Fatalf("complete() error = %v", err)
+			}
+			if got != "done" {
+				t.Errorf("complete() = %q, want %q", got, "done")
+			}
+			if *path != tt.wantPath {
+				t.Errorf("request for %s, want %s", *path, tt.wantPath)
+			}
+
+			if tt.wantPath == "/api/generate" {
+				if body.System != p.system || body.Prompt != p.user || body.Messages != nil {
+					t.Errorf("system = %q, prompt = %q, messages = %+v, want the instructions as system and the diff as prompt", body.System, body.Prompt, body.Messages)
+				}
+				return
+			}
+			if len(body.Messages) != 2 ||
+				body.Messages[0].Role != "system" || body.Messages[0].Content != p.system ||
+				body.Messages[1].Role != "user" || body.Messages[1].Content != p.user {
+				t.Errorf("messages = %+v, want the instructions as system and the diff as user message", body.Messages)
+			}
+		})
+	}
+}
+
+func TestOllamaNumCtx(t *testing.T) {
+	for tokens, want := range map[int]int{0: 2048, 100: 3072, 2048: 4096, 5000: 7168} {
+		if got := ollamaNumCtx(tokens); got != want {
+			t.Errorf("ollamaNumCtx(%d) = %d, want %d", tokens, got, want)
+		}
+	}
+
+	// The context is sized to the whole prompt unless num_ctx is configured.
+	p := prompt{system: strings.Repeat("s", 4000), user: strings.Repeat("u", 16000)}
+	tests := []struct {
+		options map[string]interface{}
+		want    float64
+	}{
+		{want: 7168},
+		{options: map[string]interface{}{"num_ctx": int64(32768), "temperature": 0.1}, want: 32768},
+	}
+	for _, tt := range tests {
+		srv, _, body := newOllamaServer(t, `{"message": {"content": "done"}, "done": true}`)
+		client, err := New(&config.AIConfig{Provider: "Ollama", OllamaURL: srv.URL, OllamaModel: "llama3", OllamaOptions: tt.options, MaxAttempts: 1})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		if _, err := client.complete(context.Background(), ProviderOllama, p, reviewSchema); err != nil {
+			t.Fatalf("complete() error = %v", err)
+		}
+		if got := body.Options["num_ctx"]; got != tt.want {
+			t.Errorf("options %v: num_ctx = %v, want %v", tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestOllamaTruncationWarning(t *testing.T) {
+	// The prompt takes about 5000 tokens.
+	p := prompt{system: strings.Repeat("s", 4000), user: strings.Repeat("u", 16000)}
+
+	tests := []struct {
+		name            string
+		promptEvalCount string
+		wantWarning     bool
+	}{
+		{name: "truncated", promptEvalCount: "2048", wantWarning: true},
+		{name: "complete", promptEvalCount: "4700"},
+		{name: "not reported", promptEvalCount: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _, _ := newOllamaServer(t, `{"message": {"content": "done"}, "done": true, "prompt_eval_count": `+tt.promptEvalCount+`}`)
+			client, err := New(&config.AIConfig{Provider: "Ollama", OllamaURL: srv.URL, OllamaModel: "llama3", MaxAttempts: 1})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			ctx, rec := withRecorder(context.Background())
+			if _, err := client.complete(ctx, ProviderOllama, p, reviewSchema); err != nil {
+				t.Fatalf("complete() error = %v", err)
+			}
+
+			warnings := rec.listWarnings()
+			if tt.wantWarning && (len(warnings) != 1 || !strings.Contains(warnings[0], "evaluated only 2048 of about 5000 prompt tokens")) {
+				t.Errorf("warnings = %q, want one about truncation", warnings)
+			}
+			if !tt.wantWarning && len(warnings) > 0 {
+				t.Errorf("warnings = %q, want none", warnings)
+			}
+		})
+	}
+}
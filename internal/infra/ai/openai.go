@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+type AuthScheme string
+
+const (
+	AuthSchemeBearer AuthScheme = "bearer"
+	AuthSchemeAPIKey AuthScheme = "api-key"
+	AuthSchemeNone   AuthScheme = "none"
+)
+
+const (
+	groqBaseURL          = "https://api.groq.com/openai/v1"
+	defaultGroqMaxTokens = 4096
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+)
+
+// chatEndpoint describes a server speaking the OpenAI chat-completions
+// protocol. Groq and OpenAI are presets of it; OpenAICompatible is built
+// entirely from configuration.
+type chatEndpoint struct {
+	name       string
+	baseURL    string
+	model      string
+	apiKey     string
+	authScheme AuthScheme
+	headers    map[string]string
+	maxTokens  int
+	format     ResponseFormat
+}
+
+// Groq only supports JSON schemas for some of its models, so it defaults to
+// the generic JSON mode.
+func newGroqEndpoint(cfg *config.AIConfig) (*chatEndpoint, error) {
+	maxTokens := cfg.GroqMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultGroqMaxTokens
+	}
+
+	format, err := parseResponseFormat(cfg.GroqResponseFormat, ResponseFormatJSONObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Groq response format: %w", err)
+	}
+
+	return &chatEndpoint{
+		name:       string(ProviderGroq),
+		baseURL:    groqBaseURL,
+		model:      cfg.GroqModel,
+		apiKey:     cfg.GroqAPIKey,
+		authScheme: AuthSchemeBearer,
+		maxTokens:  maxTokens,
+		format:     format,
+	}, nil
+}
+
+func newOpenAIEndpoint(cfg *config.AIConfig) (*chatEndpoint, error) {
+	baseURL := cfg.OpenAIBaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	headers := map[string]string{}
+	if cfg.OpenAIOrganization != "" {
+		headers["OpenAI-Organization"] = cfg.OpenAIOrganization
+	}
+
+	format, err := parseResponseFormat(cfg.OpenAIResponseFormat, ResponseFormatJSONSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAI response format: %w", err)
+	}
+
+	return &chatEndpoint{
+		name:       string(ProviderOpenAI),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		model:      cfg.OpenAIModel,
+		apiKey:     cfg.OpenAIAPIKey,
+		authScheme: AuthSchemeBearer,
+		headers:    headers,
+		format:     format,
+	}, nil
+}
+
+func newCompatibleEndpoint(cfg *config.AIConfig) (*chatEndpoint, error) {
+	authScheme := AuthScheme(strings.ToLower(cfg.CompatibleAuthScheme))
+	switch authScheme {
+	case "":
+		authScheme = AuthSchemeBearer
+	case AuthSchemeBearer, AuthSchemeAPIKey, AuthSchemeNone:
+	default:
+		return nil, fmt.Errorf("unknown auth scheme for OpenAI-compatible provider: %s", cfg.CompatibleAuthScheme)
+	}
+
+	// Servers differ too much in what they support to assume any mode.
+	format, err := parseResponseFormat(cfg.CompatibleResponseFormat, ResponseFormatNone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAI-compatible response format: %w", err)
+	}
+
+	return &chatEndpoint{
+		name:       string(ProviderOpenAICompatible),
+		baseURL:    strings.TrimSuffix(cfg.CompatibleBaseURL, "/"),
+		model:      cfg.CompatibleModel,
+		apiKey:     cfg.CompatibleAPIKey,
+		authScheme: authScheme,
+		headers:    cfg.CompatibleHeaders,
+		maxTokens:  cfg.CompatibleMaxTokens,
+		format:     format,
+	}, nil
+}
+
+func (c *Client) completeWithChatCompletions(ctx context.Context, ep *chatEndpoint, prompt prompt, schema responseSchema) (string, error) {
+	body := map[string]interface{}{
+		"model": ep.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt.system},
+			{"role": "user", "content": prompt.user},
+		},
+		"temperature": 0.7,
+	}
+	if ep.maxTokens > 0 {
+		body["max_tokens"] = ep.maxTokens
+	}
+	switch ep.format {
+	case ResponseFormatJSONSchema:
+		body["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   schema.name,
+				"strict": true,
+				"schema": schema.schema,
+			},
+		}
+	case ResponseFormatJSONObject:
+		body["response_format"] = map[string]string{"type": "json_object"}
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	resp, err := c.http.do(ctx, ep.name, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", ep.baseURL+"/chat/completions", bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		switch ep.authScheme {
+		case AuthSchemeBearer:
+			req.Header.Set("Authorization", "Bearer "+ep.apiKey)
+		case AuthSchemeAPIKey:
+			req.Header.Set("api-key", ep.apiKey)
+		}
+		for key, value := range ep.headers {
+			req.Header.Set(key, value)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode %s response: %w", ep.name, err)
+	}
+
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("invalid response format from %s", ep.name)
+	}
+
+	return result.Choices[0].Message.Content, nil
+}
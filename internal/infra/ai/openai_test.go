@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+func TestCompatibleEndpointRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		authScheme string
+		format     string
+		wantHeader string
+		wantValue  string
+		wantFormat string
+	}{
+		{
+			name:       "default bearer",
+			wantHeader: "Authorization",
+			wantValue:  "Bearer secret",
+		},
+		{
+			name:       "api-key",
+			authScheme: "API-Key",
+			format:     "json_object",
+			wantHeader: "Api-Key",
+			wantValue:  "secret",
+			wantFormat: "json_object",
+		},
+		{
+			name:       "none",
+			authScheme: "none",
+			format:     "json_schema",
+			wantFormat: "json_schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/openai/v1/chat/completions" {
+					t.Errorf("request for %s, want /openai/v1/chat/completions", r.URL.Path)
+				}
+				for _, header := range []string{"Authorization", "Api-Key"} {
+					want := ""
+					if header == tt.wantHeader {
+						want = tt.wantValue
+					}
+					if got := r.Header.Get(header); got != want {
+						t.Errorf("%s header = %q, want %q", header, got, want)
+					}
+				}
+				if got := r.Header.Get("X-Tenant"); got != "acme" {
+					t.Errorf("X-Tenant header = %q, want acme", got)
+				}
+
+				var body struct {
+					Model          string `json:"model"`
+					MaxTokens      int    `json:"max_tokens"`
+					ResponseFormat struct {
+						Type string `json:"type"`
+					} `json:"response_format"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				if body.Model != "local-model" || body.MaxTokens != 512 {
+					t.Errorf("model = %q, max_tokens = %d, want local-model and 512", body.Model, body.MaxTokens)
+				}
+				if body.ResponseFormat.Type != tt.wantFormat {
+					t.Errorf("response_format = %q, want %q", body.ResponseFormat.Type, tt.wantFormat)
+				}
+
+				_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "done"}}]}`))
+			}))
+			defer srv.Close()
+
+			client, err := New(&config.AIConfig{
+				Provider:                 "OpenAICompatible",
+				MaxAttempts:              1,
+				CompatibleBaseURL:        srv.URL + "/openai/v1/",
+				CompatibleModel:          "local-model",
+				CompatibleAPIKey:         "secret",
+				CompatibleAuthScheme:     tt.authScheme,
+				CompatibleHeaders:        map[string]string{"X-Tenant": "acme"},
+				CompatibleMaxTokens:      512,
+				CompatibleResponseFormat: tt.format,
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			got, err := client.complete(context.Background(), ProviderOpenAICompatible, prompt{system: "Review.", user: "The diff."}, reviewSchema)
+			if err != nil {
+				t.Fatalf("complete() error = %v", err)
+			}
+			if got != "done" {
+				t.Errorf("complete() = %q, want %q", got, "done")
+			}
+		})
+	}
+}
+
+func TestCompatibleEndpointUnknownAuthScheme(t *testing.T) {
+	_, err := newCompatibleEndpoint(&config.AIConfig{CompatibleAuthScheme: "basic"})
+	if err == nil {
+		t.Fatal("newCompatibleEndpoint() error = nil, want an error for an unknown auth scheme")
+	}
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+// ResponseFormat selects how a provider is held to the JSON that
+// codecritique expects: by a JSON Schema, by a generic JSON mode, or by the
+// prompt alone.
+type ResponseFormat string
+
+const (
+	ResponseFormatJSONSchema ResponseFormat = "json_schema"
+	ResponseFormatJSONObject ResponseFormat = "json_object"
+	ResponseFormatNone       ResponseFormat = "none"
+)
+
+func parseResponseFormat(value string, fallback ResponseFormat) (ResponseFormat, error) {
+	switch format := ResponseFormat(strings.ToLower(value)); format {
+	case "":
+		return fallback, nil
+	case ResponseFormatJSONSchema, ResponseFormatJSONObject, ResponseFormatNone:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown response format: %s", value)
+	}
+}
+
+// responseSchema is a named JSON Schema for the answer to a prompt.
+type responseSchema struct {
+	name   string
+	schema map[string]interface{}
+}
+
+var (
+	reviewSchema = responseSchema{
+		name: "review",
+		schema: objectSchema(
+			[]string{"review"},
+			map[string]interface{}{"review": schemaOf(reflect.TypeOf(model.Review{}))},
+		),
+	}
+	summarySchema = responseSchema{
+		name:   "summary",
+		schema: schemaOf(reflect.TypeOf(summaryResponse{})),
+	}
+)
+
+var metadataType = reflect.TypeOf(model.Metadata{})
+
+// schemaOf derives a JSON Schema from a type by its JSON tags. Every field
+// is required and pointers are nullable, as strict structured output modes
+// expect. Metadata is left out since codecritique fills it in itself.
+func schemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := schemaOf(t.Elem())
+		schema["type"] = []interface{}{schema["type"], "null"}
+		return schema
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Struct:
+		var required []string
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || field.Type == metadataType {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			required = append(required, name)
+			properties[name] = schemaOf(field.Type)
+		}
+		return objectSchema(required, properties)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func objectSchema(required []string, properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
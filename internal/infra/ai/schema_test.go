@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestReviewSchema compares the schema sent to structured output modes with
+// testdata/review_schema.json. Strict modes reject schemas with optional
+// properties or without additionalProperties, so every object must list all
+// of its properties as required, and line must be nullable rather than
+// optional. Run the test with -update after changing model.Review.
+func TestReviewSchema(t *testing.T) {
+	got, err := json.MarshalIndent(reviewSchema.schema, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "review_schema.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("review schema =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+const (
+	azureDevOpsURL    = "https://dev.azure.com"
+	azureAPIVersion   = "7.0"
+	azureChangesBatch = 1000
+)
+
+func newAzureDevOpsClient(cfg *config.GitConfig, httpClient *http.Client) *Client {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = azureDevOpsURL
+	}
+
+	return &Client{
+		provider: AzureDevOps,
+		rest: &restClient{
+			httpClient: httpClient,
+			baseURL:    strings.TrimSuffix(baseURL, "/"),
+			authorize: func(req *http.Request) {
+				// Personal access tokens go in as the password of a
+				// basic auth header with an empty user name.
+				req.SetBasicAuth("", cfg.Token)
+			},
+		},
+	}
+}
+
+// azureRepoPath resolves "organization/project/repo" into the API path of
+// the repository.
+func azureRepoPath(repository string) (string, error) {
+	parts := strings.Split(repository, "/")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return "", fmt.Errorf("invalid Azure DevOps repository %s, use the format: organization/project/repo", repository)
+	}
+	organization, project, repo := parts[0], parts[1], parts[2]
+
+	return fmt.Sprintf("/%s/%s/_apis/git/repositories/%s",
+		url.PathEscape(organization), url.PathEscape(project), url.PathEscape(repo)), nil
+}
+
+func (c *Client) fetchAzureDevOpsPR(ctx context.Context, repository, number string) (*model.PullRequest, error) {
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PR number: %w", err)
+	}
+
+	repoPath, err := azureRepoPath(repository)
+	if err != nil {
+		return nil, err
+	}
+	prPath := fmt.Sprintf("%s/pullRequests/%d", repoPath, prNumber)
+
+	var pr struct {
+		Title         string `json:"title"`
+		Description   string `json:"description"`
+		SourceRefName string `json:"sourceRefName"`
+	}
+	if err := c.rest.getJSON(ctx, prPath+"?api-version="+azureAPIVersion, &pr); err != nil {
+		return nil, fmt.Errorf("failed to fetch Azure DevOps PR: %w", err)
+	}
+
+	// The latest iteration describes the current state of the pull request.
+	var iterations struct {
+		Value []struct {
+			ID              int `json:"id"`
+			SourceRefCommit struct {
+				CommitID string `json:"commitId"`
+			} `json:"sourceRefCommit"`
+			CommonRefCommit struct {
+				CommitID string `json:"commitId"`
+			} `json:"commonRefCommit"`
+		} `json:"value"`
+	}
+	if err := c.rest.getJSON(ctx, prPath+"/iterations?api-version="+azureAPIVersion, &iterations); err != nil {
+		return nil, fmt.Errorf("failed to fetch Azure DevOps PR iterations: %w", err)
+	}
+	if len(iterations.Value) == 0 {
+		return nil, fmt.Errorf("no iterations found for Azure DevOps PR %d", prNumber)
+	}
+	iteration := iterations.Value[len(iterations.Value)-1]
+
+	changes, err := c.listAzureDevOpsChanges(ctx, fmt.Sprintf("%s/iterations/%d/changes", prPath, iteration.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Azure DevOps PR changes: %w", err)
+	}
+
+	// The API does not return patches, so every file is diffed between the
+	// merge base and the head of the pull request.
+	var files []model.File
+	for _, change := range changes {
+		if change.Item.IsFolder || change.Item.GitObjectType == "tree" {
+			continue
+		}
+
+		file, err := c.azureDevOpsFile(ctx, repoPath, change, iteration.CommonRefCommit.CommitID, iteration.SourceRefCommit.CommitID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return &model.PullRequest{
+		Title:       pr.Title,
+		Branch:      strings.TrimPrefix(pr.SourceRefName, "refs/heads/"),
+		Description: pr.Description,
+		HeadSHA:     iteration.SourceRefCommit.CommitID,
+		Files:       files,
+	}, nil
+}
+
+type azureChange struct {
+	ChangeType   string `json:"changeType"`
+	OriginalPath string `json:"originalPath"`
+	Item         struct {
+		Path          string `json:"path"`
+		GitObjectType string `json:"gitObjectType"`
+		IsFolder      bool   `json:"isFolder"`
+	} `json:"item"`
+}
+
+func (c *Client) listAzureDevOpsChanges(ctx context.Context, path string) ([]azureChange, error) {
+	var changes []azureChange
+	for skip := 0; ; skip += azureChangesBatch {
+		var page struct {
+			ChangeEntries []azureChange `json:"changeEntries"`
+			NextSkip      int           `json:"nextSkip"`
+		}
+		query := fmt.Sprintf("?api-version=%s&$top=%d&$skip=%d", azureAPIVersion, azureChangesBatch, skip)
+		if err := c.rest.getJSON(ctx, path+query, &page); err != nil {
+			return nil, err
+		}
+		changes = append(changes, page.ChangeEntries...)
+
+		if page.NextSkip == 0 || len(page.ChangeEntries) == 0 {
+			return changes, nil
+		}
+	}
+}
+
+func (c *Client) azureDevOpsFile(ctx context.Context, repoPath string, change azureChange, baseCommit, headCommit string) (model.File, error) {
+	// Change types are combined, as in "edit, rename".
+	changeType := change.ChangeType
+	path := strings.TrimPrefix(change.Item.Path, "/")
+	file := model.File{OldPath: path, NewPath: path, Status: model.FileModified}
+	switch {
+	case strings.Contains(changeType, "add"):
+		file.OldPath, file.Status = "", model.FileAdded
+	case strings.Contains(changeType, "delete"):
+		file.NewPath, file.Status = "", model.FileDeleted
+	case strings.Contains(changeType, "rename"):
+		file.OldPath, file.Status = strings.TrimPrefix(change.OriginalPath, "/"), model.FileRenamed
+	}
+
+	var oldContent, newContent string
+	var oldBinary, newBinary bool
+	var err error
+	if file.OldPath != "" {
+		oldContent, oldBinary, err = c.azureDevOpsContent(ctx, repoPath, file.OldPath, baseCommit)
+		if err != nil {
+			return model.File{}, err
+		}
+	}
+	if file.NewPath != "" {
+		newContent, newBinary, err = c.azureDevOpsContent(ctx, repoPath, file.NewPath, headCommit)
+		if err != nil {
+			return model.File{}, err
+		}
+	}
+
+	if oldBinary || newBinary {
+		file.Status = model.FileBinary
+		return file, nil
+	}
+
+	file.Hunks = diffHunks(oldContent, newContent)
+	return file, nil
+}
+
+func (c *Client) azureDevOpsContent(ctx context.Context, repoPath, path, commit string) (string, bool, error) {
+	query := url.Values{}
+	query.Set("path", "/"+path)
+	query.Set("versionDescriptor.version", commit)
+	query.Set("versionDescriptor.versionType", "commit")
+	query.Set("includeContent", "true")
+	query.Set("api-version", azureAPIVersion)
+
+	var item struct {
+		Content         string `json:"content"`
+		ContentMetadata struct {
+			IsBinary bool `json:"isBinary"`
+		} `json:"contentMetadata"`
+	}
+	if err := c.rest.getJSON(ctx, repoPath+"/items?"+query.Encode(), &item); err != nil {
+		return "", false, fmt.Errorf("failed to fetch %s at %s: %w", path, commit, err)
+	}
+
+	return item.Content, item.ContentMetadata.IsBinary, nil
+}
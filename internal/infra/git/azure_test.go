@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestFetchAzureDevOpsPR(t *testing.T) {
+	const repoPath = "/tfs/org/proj/_apis/git/repositories/repo"
+
+	// The changes come in two pages; the content of every file is read at
+	// the merge base and at the head of the pull request.
+	changes := map[string]string{
+		"0": `{"changeEntries": [
+			{"changeType": "edit", "item": {"path": "/main.go", "gitObjectType": "blob"}},
+			{"changeType": "add", "item": {"path": "/cmd", "gitObjectType": "tree", "isFolder": true}}
+		], "nextSkip": 1000}`,
+		"1000": `{"changeEntries": [
+			{"changeType": "add", "item": {"path": "/cmd/tool.go", "gitObjectType": "blob"}},
+			{"changeType": "edit, rename", "originalPath": "/logo.png", "item": {"path": "/img/logo.png", "gitObjectType": "blob"}}
+		], "nextSkip": 0}`,
+	}
+	contents := map[string]map[string]interface{}{
+		"/main.go@base":      {"content": "package main\n\nfunc main() {}\n"},
+		"/main.go@head":      {"content": "package main\n\nfunc main() {\n\trun()\n}\n"},
+		"/cmd/tool.go@head":  {"content": "package cmd\n"},
+		"/logo.png@base":     {"contentMetadata": map[string]bool{"isBinary": true}},
+		"/img/logo.png@head": {"contentMetadata": map[string]bool{"isBinary": true}},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "" || password != "t" {
+			t.Errorf("%s: want basic auth with the token as password", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("api-version") != azureAPIVersion {
+			t.Errorf("%s: api-version = %q, want %s", r.URL.Path, query.Get("api-version"), azureAPIVersion)
+		}
+
+		switch r.URL.Path {
+		case repoPath + "/pullRequests/17":
+			_, _ = w.Write([]byte(`{"title": "Add the tool", "description": "Adds a tool.", "sourceRefName": "refs/heads/feature/tool"}`))
+		case repoPath + "/pullRequests/17/iterations":
+			_, _ = w.Write([]byte(`{"value": [
+				{"id": 1, "sourceRefCommit": {"commitId": "old"}, "commonRefCommit": {"commitId": "base"}},
+				{"id": 2, "sourceRefCommit": {"commitId": "head"}, "commonRefCommit": {"commitId": "base"}}
+			]}`))
+		case repoPath + "/pullRequests/17/iterations/2/changes":
+			page, ok := changes[query.Get("$skip")]
+			if !ok {
+				t.Errorf("unexpected page $skip=%s", query.Get("$skip"))
+			}
+			_, _ = w.Write([]byte(page))
+		case repoPath + "/items":
+			item, ok := contents[query.Get("path")+"@"+query.Get("versionDescriptor.version")]
+			if !ok {
+				t.Errorf("unexpected item %s at %s", query.Get("path"), query.Get("versionDescriptor.version"))
+			}
+			_ = json.NewEncoder(w).Encode(item)
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := New(&config.GitConfig{Provider: string(AzureDevOps), Token: "t", BaseURL: srv.URL + "/tfs/"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, err := client.FetchPullRequest(context.Background(), "org/proj/repo", "17")
+	if err != nil {
+		t.Fatalf("FetchPullRequest() error = %v", err)
+	}
+
+	want := &model.PullRequest{
+		Title:       "Add the tool",
+		Branch:      "feature/tool",
+		Description: "Adds a tool.",
+		HeadSHA:     "head",
+		Files: []model.File{
+			{
+				OldPath: "main.go",
+				NewPath: "main.go",
+				Status:  model.FileModified,
+				Hunks:   diffHunks("package main\n\nfunc main() {}\n", "package main\n\nfunc main() {\n\trun()\n}\n"),
+			},
+			{
+				NewPath: "cmd/tool.go",
+				Status:  model.FileAdded,
+				Hunks:   diffHunks("", "package cmd\n"),
+			},
+			{
+				OldPath: "logo.png",
+				NewPath: "img/logo.png",
+				Status:  model.FileBinary,
+			},
+		},
+	}
+	if !reflect.DeepEqual(pr, want) {
+		t.Errorf("FetchPullRequest() =\n%+v\nwant\n%+v", pr, want)
+	}
+	if len(pr.Files[0].Hunks) != 1 || len(pr.Files[1].Hunks) != 1 {
+		t.Errorf("hunks = %+v, want one per text file", pr.Files)
+	}
+}
@@ -0,0 +1,182 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+const bitbucketCloudURL = "https://api.bitbucket.org/2.0"
+
+func newBitbucketClient(provider Provider, cfg *config.GitConfig, httpClient *http.Client) (*Client, error) {
+	baseURL := strings.TrimSuffix(cfg.BaseURL, "/")
+	switch {
+	case provider == Bitbucket && baseURL == "":
+		baseURL = bitbucketCloudURL
+	case provider == BitbucketServer && baseURL == "":
+		return nil, fmt.Errorf("base_url is required for Bitbucket Server")
+	case provider == BitbucketServer:
+		baseURL += "/rest/api/1.0"
+	}
+
+	return &Client{
+		provider: provider,
+		rest: &restClient{
+			httpClient: httpClient,
+			baseURL:    baseURL,
+			authorize: func(req *http.Request) {
+				// App passwords need the account name; access tokens
+				// are sent on their own.
+				if cfg.Username != "" {
+					req.SetBasicAuth(cfg.Username, cfg.Token)
+				} else {
+					req.Header.Set("Authorization", "Bearer "+cfg.Token)
+				}
+			},
+		},
+	}, nil
+}
+
+// bitbucketPRPath returns the API path of a pull request. For Bitbucket
+// Cloud, the owner of the repository is the workspace; for Bitbucket Server,
+// the project key.
+func (c *Client) bitbucketPRPath(repository, number string) (string, error) {
+	owner, repo, err := splitRepository(repository)
+	if err != nil {
+		return "", err
+	}
+
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return "", fmt.Errorf("invalid PR number: %w", err)
+	}
+
+	if c.provider == BitbucketServer {
+		return fmt.Sprintf("/projects/%s/repos/%s/pull-requests/%d", url.PathEscape(owner), url.PathEscape(repo), prNumber), nil
+	}
+	return fmt.Sprintf("/repositories/%s/%s/pullrequests/%d", url.PathEscape(owner), url.PathEscape(repo), prNumber), nil
+}
+
+func (c *Client) fetchBitbucketPR(ctx context.Context, repository, number string) (*model.PullRequest, error) {
+	path, err := c.bitbucketPRPath(repository, number)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		// Bitbucket Cloud
+		Source struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Commit struct {
+				Hash string `json:"hash"`
+			} `json:"commit"`
+		} `json:"source"`
+		// Bitbucket Server
+		FromRef struct {
+			DisplayID    string `json:"displayId"`
+			LatestCommit string `json:"latestCommit"`
+		} `json:"fromRef"`
+	}
+	if err := c.rest.getJSON(ctx, path, &pr); err != nil {
+		return nil, fmt.Errorf("failed to fetch Bitbucket PR: %w", err)
+	}
+
+	diffPath := path + "/diff"
+	if c.provider == BitbucketServer {
+		diffPath = path + ".diff"
+	}
+	diff, err := c.rest.getText(ctx, diffPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Bitbucket PR diff: %w", err)
+	}
+
+	branch, head := pr.Source.Branch.Name, pr.Source.Commit.Hash
+	if c.provider == BitbucketServer {
+		branch, head = pr.FromRef.DisplayID, pr.FromRef.LatestCommit
+	}
+
+	return &model.PullRequest{
+		Title:       pr.Title,
+		Branch:      branch,
+		Description: pr.Description,
+		HeadSHA:     head,
+		Files:       parseUnifiedDiff(diff),
+	}, nil
+}
+
+func (c *Client) publishBitbucketReview(ctx context.Context, repository, number string, review *model.Review) error {
+	path, err := c.bitbucketPRPath(repository, number)
+	if err != nil {
+		return err
+	}
+	commentsPath := path + "/comments"
+
+	lines := mapDiffLines(review.PullRequest.Files)
+	var inline []interface{}
+	var unanchored []model.Feedback
+	for _, feedback := range review.CodeFeedback {
+		if feedback.Line == nil {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		line, ok := lines[feedback.File][*feedback.Line]
+		if !ok {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		if c.provider == BitbucketServer {
+			lineType := "ADDED"
+			if line.oldLine > 0 {
+				lineType = "CONTEXT"
+			}
+			inline = append(inline, map[string]interface{}{
+				"text": feedback.Suggestion,
+				"anchor": map[string]interface{}{
+					"path":     feedback.File,
+					"line":     *feedback.Line,
+					"lineType": lineType,
+					"fileType": "TO",
+					"diffType": "EFFECTIVE",
+				},
+			})
+			continue
+		}
+
+		inline = append(inline, map[string]interface{}{
+			"content": map[string]string{"raw": feedback.Suggestion},
+			"inline": map[string]interface{}{
+				"path": feedback.File,
+				"to":   *feedback.Line,
+			},
+		})
+	}
+
+	body := reviewBody(review, unanchored)
+	var summary interface{} = map[string]interface{}{"content": map[string]string{"raw": body}}
+	if c.provider == BitbucketServer {
+		summary = map[string]string{"text": body}
+	}
+	if err := c.rest.postJSON(ctx, commentsPath, summary); err != nil {
+		return fmt.Errorf("failed to create Bitbucket PR comment: %w", err)
+	}
+
+	for _, comment := range inline {
+		if err := c.rest.postJSON(ctx, commentsPath, comment); err != nil {
+			return fmt.Errorf("failed to create Bitbucket inline comment: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+const testDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++
+ func main() {}
+`
+
+func TestFetchBitbucketPR(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   Provider
+		basePath   string
+		username   string
+		repository string
+		routes     map[string]string
+		wantAuth   string
+		wantBranch string
+		wantHead   string
+	}{
+		{
+			name:       "cloud",
+			provider:   Bitbucket,
+			repository: "w/r",
+			routes: map[string]string{
+				"/repositories/w/r/pullrequests/5": `{
+					"title": "Add main",
+					"description": "Adds the entry point.",
+					"source": {"branch": {"name": "feature"}, "commit": {"hash": "abc123"}}
+				}`,
+				// Bitbucket Cloud redirects to the diff of the commit range.
+				"/repositories/w/r/pullrequests/5/diff":     "redirect:/repositories/w/r/diff/w/r:abc123..def456",
+				"/repositories/w/r/diff/w/r:abc123..def456": testDiff,
+			},
+			wantAuth:   "Bearer t",
+			wantBranch: "feature",
+			wantHead:   "abc123",
+		},
+		{
+			name:       "server with an app password",
+			provider:   BitbucketServer,
+			basePath:   "/bitbucket",
+			username:   "jane",
+			repository: "KEY/r",
+			routes: map[string]string{
+				"/bitbucket/rest/api/1.0/projects/KEY/repos/r/pull-requests/5": `{
+					"title": "Add main",
+					"description": "Adds the entry point.",
+					"fromRef": {"displayId": "feature", "latestCommit": "abc123"}
+				}`,
+				"/bitbucket/rest/api/1.0/projects/KEY/repos/r/pull-requests/5.diff": testDiff,
+			},
+			wantAuth:   "Basic amFuZTp0",
+			wantBranch: "feature",
+			wantHead:   "abc123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != tt.wantAuth {
+					t.Errorf("%s: Authorization = %q, want %q", r.URL.Path, got, tt.wantAuth)
+				}
+				body, ok := tt.routes[r.URL.Path]
+				if !ok {
+					t.Errorf("unexpected request for %s", r.URL.Path)
+					http.NotFound(w, r)
+					return
+				}
+				if location, ok := strings.CutPrefix(body, "redirect:"); ok {
+					http.Redirect(w, r, location, http.StatusFound)
+					return
+				}
+				_, _ = w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			client, err := New(&config.GitConfig{
+				Provider: string(tt.provider),
+				Token:    "t",
+				Username: tt.username,
+				BaseURL:  srv.URL + tt.basePath,
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			pr, err := client.FetchPullRequest(context.Background(), tt.repository, "5")
+			if err != nil {
+				t.Fatalf("FetchPullRequest() error = %v", err)
+			}
+
+			want := &model.PullRequest{
+				Title:       "Add main",
+				Branch:      tt.wantBranch,
+				Description: "Adds the entry point.",
+				HeadSHA:     tt.wantHead,
+				Files:       parseUnifiedDiff(testDiff),
+			}
+			if !reflect.DeepEqual(pr, want) {
+				t.Errorf("FetchPullRequest() =\n%+v\nwant\n%+v", pr, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// parseUnifiedDiff splits the output of "git diff" into its files. Text
+// outside the file headers and hunks, such as the headers of a mail
+// produced by "git format-patch", is ignored.
+func parseUnifiedDiff(raw string) []model.File {
+	var files []model.File
+	var current *model.File
+	var hunks *hunkParser
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Hunks = hunks.hunks
+		files = append(files, finishFile(*current))
+		current, hunks = nil, nil
+	}
+
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		switch {
+		case hunks != nil && hunks.inHunk():
+			hunks.add(line)
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			oldPath, newPath := splitDiffGitHeader(strings.TrimPrefix(line, "diff --git "))
+			current = &model.File{OldPath: oldPath, NewPath: newPath}
+			hunks = &hunkParser{}
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			// Plain "diff -u" output has no "diff --git" line, so the
+			// file header starts here.
+			if current == nil || len(hunks.hunks) > 0 {
+				flush()
+				current = &model.File{}
+				hunks = &hunkParser{}
+			}
+			current.OldPath = diffPath(strings.TrimPrefix(line, "--- "))
+			if current.OldPath == "" {
+				current.Status = model.FileAdded
+			}
+		case current == nil:
+		case strings.HasPrefix(line, "@@"), strings.HasPrefix(line, `\`):
+			hunks.add(line)
+		case len(hunks.hunks) > 0:
+			// Whatever follows the last hunk of a file up to the next file,
+			// like the signature of a format-patch mail, is not part of it.
+		case strings.HasPrefix(line, "+++ "):
+			current.NewPath = diffPath(strings.TrimPrefix(line, "+++ "))
+			if current.NewPath == "" {
+				current.Status = model.FileDeleted
+			}
+		case strings.HasPrefix(line, "new file mode"):
+			current.Status = model.FileAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.Status = model.FileDeleted
+		case strings.HasPrefix(line, "rename from "):
+			current.OldPath = strings.TrimPrefix(line, "rename from ")
+			current.Status = model.FileRenamed
+		case strings.HasPrefix(line, "rename to "):
+			current.NewPath = strings.TrimPrefix(line, "rename to ")
+			current.Status = model.FileRenamed
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			current.Status = model.FileBinary
+		}
+	}
+	flush()
+
+	return files
+}
+
+// newFile builds a file from the hunks of a single file, as the GitHub and
+// GitLab APIs return them.
+func newFile(oldPath, newPath string, status model.FileStatus, patch string) model.File {
+	file := model.File{
+		OldPath: oldPath,
+		NewPath: newPath,
+		Status:  status,
+		Hunks:   parseHunks(patch),
+	}
+	if strings.HasPrefix(patch, "Binary files ") {
+		file.Status = model.FileBinary
+	}
+
+	return file
+}
+
+func parseHunks(patch string) []model.Hunk {
+	p := &hunkParser{}
+	for _, line := range strings.Split(patch, "\n") {
+		p.add(line)
+	}
+
+	return p.hunks
+}
+
+// finishFile fills in what the headers of a file left open.
+func finishFile(file model.File) model.File {
+	switch file.Status {
+	case model.FileAdded:
+		file.OldPath = ""
+	case model.FileDeleted:
+		file.NewPath = ""
+	case "":
+		file.Status = model.FileModified
+		if file.OldPath != file.NewPath {
+			file.Status = model.FileRenamed
+		}
+	}
+
+	return file
+}
+
+// hunkParser collects the hunks of a single file. It follows the line
+// counts of the hunk headers, so lines after the end of a hunk are never
+// mistaken for part of it.
+type hunkParser struct {
+	hunks            []model.Hunk
+	oldLeft, newLeft int
+	nextOld, nextNew int
+}
+
+func (p *hunkParser) inHunk() bool {
+	return p.oldLeft > 0 || p.newLeft > 0
+}
+
+func (p *hunkParser) add(line string) {
+	if m := hunkHeader.FindStringSubmatch(line); m != nil && !p.inHunk() {
+		hunk := model.Hunk{
+			OldStart: atoi(m[1]),
+			OldLines: atoiDefault(m[2], 1),
+			NewStart: atoi(m[3]),
+			NewLines: atoiDefault(m[4], 1),
+			Section:  m[5],
+		}
+		p.hunks = append(p.hunks, hunk)
+		p.oldLeft, p.newLeft = hunk.OldLines, hunk.NewLines
+		p.nextOld, p.nextNew = hunk.OldStart, hunk.NewStart
+		return
+	}
+	if len(p.hunks) == 0 {
+		return
+	}
+
+	hunk := &p.hunks[len(p.hunks)-1]
+	switch {
+	case strings.HasPrefix(line, `\`):
+		hunk.Lines = append(hunk.Lines, model.Line{Kind: model.LineNoNewline, Content: strings.TrimPrefix(line, `\ `)})
+	case !p.inHunk():
+	case strings.HasPrefix(line, "+"):
+		hunk.Lines = append(hunk.Lines, model.Line{Kind: model.LineAdded, Content: line[1:], NewLine: p.nextNew})
+		p.nextNew++
+		p.newLeft--
+	case strings.HasPrefix(line, "-"):
+		hunk.Lines = append(hunk.Lines, model.Line{Kind: model.LineDeleted, Content: line[1:], OldLine: p.nextOld})
+		p.nextOld++
+		p.oldLeft--
+	default:
+		// Some tools strip the leading space of empty context lines.
+		hunk.Lines = append(hunk.Lines, model.Line{Kind: model.LineContext, Content: strings.TrimPrefix(line, " "), OldLine: p.nextOld, NewLine: p.nextNew})
+		p.nextOld++
+		p.nextNew++
+		p.oldLeft--
+		p.newLeft--
+	}
+}
+
+// splitDiffGitHeader extracts both paths from the "a/old b/new" part of a
+// "diff --git" line.
+func splitDiffGitHeader(header string) (string, string) {
+	if i := strings.Index(header, " b/"); i >= 0 && strings.HasPrefix(header, "a/") {
+		return header[2:i], header[i+3:]
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) != 2 {
+		return header, header
+	}
+
+	return fields[0], fields[1]
+}
+
+// diffPath reads a path from a "---" or "+++" line. /dev/null becomes the
+// empty path.
+func diffPath(path string) string {
+	path, _, _ = strings.Cut(path, "\t")
+	switch {
+	case path == "/dev/null":
+		return ""
+	case strings.HasPrefix(path, "a/"), strings.HasPrefix(path, "b/"):
+		return path[2:]
+	default:
+		return path
+	}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	return atoi(s)
+}
@@ -0,0 +1,173 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []model.File
+	}{
+		{
+			name: "modified",
+			raw: `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@ package main
+ func main() {
+-	println("hello")
++	println("world")
+ }
+`,
+			want: []model.File{{
+				OldPath: "main.go",
+				NewPath: "main.go",
+				Status:  model.FileModified,
+				Hunks: []model.Hunk{{
+					OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3,
+					Section: "package main",
+					Lines: []model.Line{
+						{Kind: model.LineContext, Content: "func main() {", OldLine: 1, NewLine: 1},
+						{Kind: model.LineDeleted, Content: `	println("hello")`, OldLine: 2},
+						{Kind: model.LineAdded, Content: `	println("world")`, NewLine: 2},
+						{Kind: model.LineContext, Content: "}", OldLine: 3, NewLine: 3},
+					},
+				}},
+			}},
+		},
+		{
+			name: "added without newline at end of file",
+			raw: `diff --git a/README b/README
+new file mode 100644
+index 0000000..e69de29
+--- /dev/null
++++ b/README
+@@ -0,0 +1 @@
++hello
+\ No newline at end of file
+`,
+			want: []model.File{{
+				NewPath: "README",
+				Status:  model.FileAdded,
+				Hunks: []model.Hunk{{
+					OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1,
+					Lines: []model.Line{
+						{Kind: model.LineAdded, Content: "hello", NewLine: 1},
+						{Kind: model.LineNoNewline, Content: "No newline at end of file"},
+					},
+				}},
+			}},
+		},
+		{
+			name: "deleted",
+			raw: `diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+			want: []model.File{{
+				OldPath: "old.txt",
+				Status:  model.FileDeleted,
+				Hunks: []model.Hunk{{
+					OldStart: 1, OldLines: 2, NewStart: 0, NewLines: 0,
+					Lines: []model.Line{
+						{Kind: model.LineDeleted, Content: "a", OldLine: 1},
+						{Kind: model.LineDeleted, Content: "b", OldLine: 2},
+					},
+				}},
+			}},
+		},
+		{
+			name: "renamed and binary",
+			raw: `diff --git a/a.txt b/b.txt
+similarity index 100%
+rename from a.txt
+rename to b.txt
+diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+`,
+			want: []model.File{
+				{OldPath: "a.txt", NewPath: "b.txt", Status: model.FileRenamed},
+				{OldPath: "logo.png", NewPath: "logo.png", Status: model.FileBinary},
+			},
+		},
+		{
+			name: "plain diff -u with a line that looks like a header",
+			raw: `--- a.txt	2024-01-01 00:00:00
++++ a.txt	2024-01-02 00:00:00
+@@ -1,2 +1,2 @@
+--- not a header
++++ not a header either
+ end
+`,
+			want: []model.File{{
+				OldPath: "a.txt",
+				NewPath: "a.txt",
+				Status:  model.FileModified,
+				Hunks: []model.Hunk{{
+					OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2,
+					Lines: []model.Line{
+						{Kind: model.LineDeleted, Content: "-- not a header", OldLine: 1},
+						{Kind: model.LineAdded, Content: "++ not a header either", NewLine: 1},
+						{Kind: model.LineContext, Content: "end", OldLine: 2, NewLine: 2},
+					},
+				}},
+			}},
+		},
+		{
+			name: "format-patch mail",
+			raw: `From 0123456789abcdef0123456789abcdef01234567 Mon Sep 17 00:00:00 2001
+From: Jane Doe <jane@example.com>
+Subject: [PATCH] Fix typo
+
+---
+ a.txt | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-teh
++the
+--
+2.44.0
+`,
+			want: []model.File{{
+				OldPath: "a.txt",
+				NewPath: "a.txt",
+				Status:  model.FileModified,
+				Hunks: []model.Hunk{{
+					OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+					Lines: []model.Line{
+						{Kind: model.LineDeleted, Content: "teh", OldLine: 1},
+						{Kind: model.LineAdded, Content: "the", NewLine: 1},
+					},
+				}},
+			}},
+		},
+		{
+			name: "no changes",
+			raw:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseUnifiedDiff(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUnifiedDiff() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
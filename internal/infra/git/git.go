@@ -16,31 +16,59 @@ import (
 type Provider string
 
 const (
-	GitHub Provider = "GitHub"
-	GitLab Provider = "GitLab"
+	GitHub          Provider = "GitHub"
+	GitLab          Provider = "GitLab"
+	Bitbucket       Provider = "Bitbucket"
+	BitbucketServer Provider = "BitbucketServer"
+	Gitea           Provider = "Gitea"
+	AzureDevOps     Provider = "AzureDevOps"
 )
 
+// githubMaxListedFiles is the number of files after which GitHub stops
+// listing the files of a pull request.
+const githubMaxListedFiles = 3000
+
 type Client struct {
 	provider     Provider
 	githubClient *github.Client
 	gitlabClient *gitlab.Client
+	rest         *restClient
 }
 
 func New(cfg *config.GitConfig) (*Client, error) {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	switch Provider(cfg.Provider) {
 	case GitHub:
-		ctx := context.Background()
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: cfg.Token},
 		)
 		tc := oauth2.NewClient(ctx, ts)
 		client := github.NewClient(tc)
+		if cfg.BaseURL != "" {
+			uploadURL := cfg.UploadURL
+			if uploadURL == "" {
+				uploadURL = cfg.BaseURL
+			}
+			client, err = client.WithEnterpriseURLs(cfg.BaseURL, uploadURL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure GitHub Enterprise URLs: %w", err)
+			}
+		}
 		return &Client{
 			provider:     GitHub,
 			githubClient: client,
 		}, nil
 	case GitLab:
-		client, err := gitlab.NewClient(cfg.Token)
+		options := []gitlab.ClientOptionFunc{gitlab.WithHTTPClient(httpClient)}
+		if cfg.BaseURL != "" {
+			options = append(options, gitlab.WithBaseURL(cfg.BaseURL))
+		}
+		client, err := gitlab.NewClient(cfg.Token, options...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create GitLab client: %w", err)
 		}
@@ -48,23 +76,44 @@ func New(cfg *config.GitConfig) (*Client, error) {
 			provider:     GitLab,
 			gitlabClient: client,
 		}, nil
+	case Bitbucket, BitbucketServer:
+		return newBitbucketClient(Provider(cfg.Provider), cfg, httpClient)
+	case Gitea:
+		return newGiteaClient(cfg, httpClient)
+	case AzureDevOps:
+		return newAzureDevOpsClient(cfg, httpClient), nil
 	default:
 		return nil, fmt.Errorf("unsupported Git provider: %s", cfg.Provider)
 	}
 }
 
-func (c *Client) FetchPullRequest(ctx context.Context, owner, repo, number string) (*model.PullRequest, error) {
+// FetchPullRequest fetches a pull request of the repository, given as its
+// full path. GitHub, Bitbucket and Gitea expect "owner/repo", Azure DevOps
+// "organization/project/repo"; GitLab accepts namespaces of any depth as
+// well as numeric project IDs.
+func (c *Client) FetchPullRequest(ctx context.Context, repository, number string) (*model.PullRequest, error) {
 	switch c.provider {
 	case GitHub:
-		return c.fetchGitHubPR(ctx, owner, repo, number)
+		return c.fetchGitHubPR(ctx, repository, number)
 	case GitLab:
-		return c.fetchGitLabMR(owner, repo, number)
+		return c.fetchGitLabMR(ctx, repository, number)
+	case Bitbucket, BitbucketServer:
+		return c.fetchBitbucketPR(ctx, repository, number)
+	case Gitea:
+		return c.fetchGiteaPR(ctx, repository, number)
+	case AzureDevOps:
+		return c.fetchAzureDevOpsPR(ctx, repository, number)
 	default:
 		return nil, fmt.Errorf("unsupported Git provider: %s", c.provider)
 	}
 }
 
-func (c *Client) fetchGitHubPR(ctx context.Context, owner, repo, number string) (*model.PullRequest, error) {
+func (c *Client) fetchGitHubPR(ctx context.Context, repository, number string) (*model.PullRequest, error) {
+	owner, repo, err := splitRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+
 	prNumber, err := strconv.Atoi(number)
 	if err != nil {
 		return nil, fmt.Errorf("invalid PR number: %w", err)
@@ -76,57 +125,135 @@ func (c *Client) fetchGitHubPR(ctx context.Context, owner, repo, number string)
 	}
 
 	// Fetch the diff
-	opt := &github.ListOptions{
-		PerPage: 100,
-	}
-	files, _, err := c.githubClient.PullRequests.ListFiles(ctx, owner, repo, prNumber, opt)
+	files, err := c.listGitHubPRFiles(ctx, owner, repo, prNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch GitHub PR files: %w", err)
 	}
 
-	var diffBuilder strings.Builder
+	var warnings []string
+	if len(files) < pr.GetChangedFiles() {
+		warnings = append(warnings, fmt.Sprintf(
+			"GitHub lists at most %d files per pull request; only %d of %d changed files were reviewed",
+			githubMaxListedFiles, len(files), pr.GetChangedFiles(),
+		))
+	}
 	for _, file := range files {
-		diffBuilder.WriteString(fmt.Sprintf("## file: '%s'\n\n", file.GetFilename()))
-		diffBuilder.WriteString(file.GetPatch())
-		diffBuilder.WriteString("\n\n")
+		// Pure renames legitimately come without a patch.
+		if file.GetPatch() == "" && !(file.GetStatus() == "renamed" && file.GetChanges() == 0) {
+			warnings = append(warnings, fmt.Sprintf(
+				"GitHub returned no patch for %s because it is binary or too large; it was not reviewed",
+				file.GetFilename(),
+			))
+		}
 	}
 
 	return &model.PullRequest{
 		Title:       pr.GetTitle(),
 		Branch:      pr.GetHead().GetRef(),
 		Description: pr.GetBody(),
-		Diff:        diffBuilder.String(),
+		HeadSHA:     pr.GetHead().GetSHA(),
+		Files:       githubFiles(files),
+		Warnings:    warnings,
 	}, nil
 }
 
-func (c *Client) fetchGitLabMR(owner, repo, number string) (*model.PullRequest, error) {
+func (c *Client) listGitHubPRFiles(ctx context.Context, owner, repo string, number int) ([]*github.CommitFile, error) {
+	opt := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	var files []*github.CommitFile
+	for {
+		page, resp, err := c.githubClient.PullRequests.ListFiles(ctx, owner, repo, number, opt)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, page...)
+
+		if resp.NextPage == 0 {
+			return files, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+func (c *Client) fetchGitLabMR(ctx context.Context, project, number string) (*model.PullRequest, error) {
 	mrNumber, err := strconv.Atoi(number)
 	if err != nil {
 		return nil, fmt.Errorf("invalid MR number: %w", err)
 	}
 
-	mr, _, err := c.gitlabClient.MergeRequests.GetMergeRequest(owner+"/"+repo, mrNumber, nil)
+	mr, _, err := c.gitlabClient.MergeRequests.GetMergeRequest(project, mrNumber, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch GitLab MR: %w", err)
 	}
 
 	// Fetch the diff
-	changes, _, err := c.gitlabClient.MergeRequests.ListMergeRequestDiffs(owner+"/"+repo, mrNumber, nil)
+	changes, err := c.listGitLabMRDiffs(ctx, project, mrNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch GitLab MR changes: %w", err)
 	}
 
-	var diffBuilder strings.Builder
-	for _, change := range changes {
-		diffBuilder.WriteString(fmt.Sprintf("## file: '%s'\n\n", change.NewPath))
-		diffBuilder.WriteString(change.Diff)
-		diffBuilder.WriteString("\n\n")
-	}
-
 	return &model.PullRequest{
 		Title:       mr.Title,
 		Branch:      mr.SourceBranch,
 		Description: mr.Description,
-		Diff:        diffBuilder.String(),
+		HeadSHA:     mr.DiffRefs.HeadSha,
+		BaseSHA:     mr.DiffRefs.BaseSha,
+		StartSHA:    mr.DiffRefs.StartSha,
+		Files:       gitlabFiles(changes),
 	}, nil
 }
+
+func githubFiles(files []*github.CommitFile) []model.File {
+	result := make([]model.File, 0, len(files))
+	for _, file := range files {
+		oldPath, newPath := file.GetFilename(), file.GetFilename()
+		var status model.FileStatus
+		switch file.GetStatus() {
+		case "added":
+			oldPath, status = "", model.FileAdded
+		case "removed":
+			newPath, status = "", model.FileDeleted
+		case "renamed":
+			oldPath, status = file.GetPreviousFilename(), model.FileRenamed
+		default:
+			status = model.FileModified
+		}
+		result = append(result, newFile(oldPath, newPath, status, file.GetPatch()))
+	}
+
+	return result
+}
+
+func gitlabFiles(changes []*gitlab.MergeRequestDiff) []model.File {
+	result := make([]model.File, 0, len(changes))
+	for _, change := range changes {
+		oldPath, newPath := change.OldPath, change.NewPath
+		var status model.FileStatus
+		switch {
+		case change.NewFile:
+			oldPath, status = "", model.FileAdded
+		case change.DeletedFile:
+			newPath, status = "", model.FileDeleted
+		case change.RenamedFile:
+			status = model.FileRenamed
+		default:
+			status = model.FileModified
+		}
+		result = append(result, newFile(oldPath, newPath, status, change.Diff))
+	}
+
+	return result
+}
+
+// splitRepository splits "owner/repo" for the providers that address
+// repositories by exactly two segments.
+func splitRepository(repository string) (string, string, error) {
+	owner, repo, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("invalid repository %s, use the format: owner/repo", repository)
+	}
+
+	return owner, repo, nil
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func newGiteaClient(cfg *config.GitConfig, httpClient *http.Client) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base_url is required for Gitea")
+	}
+
+	return &Client{
+		provider: Gitea,
+		rest: &restClient{
+			httpClient: httpClient,
+			baseURL:    strings.TrimSuffix(cfg.BaseURL, "/") + "/api/v1",
+			authorize: func(req *http.Request) {
+				req.Header.Set("Authorization", "token "+cfg.Token)
+			},
+		},
+	}, nil
+}
+
+type giteaPR struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	} `json:"head"`
+}
+
+func giteaPRPath(repository, number string) (string, error) {
+	owner, repo, err := splitRepository(repository)
+	if err != nil {
+		return "", err
+	}
+
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return "", fmt.Errorf("invalid PR number: %w", err)
+	}
+
+	return fmt.Sprintf("/repos/%s/%s/pulls/%d", url.PathEscape(owner), url.PathEscape(repo), prNumber), nil
+}
+
+func (c *Client) fetchGiteaPR(ctx context.Context, repository, number string) (*model.PullRequest, error) {
+	path, err := giteaPRPath(repository, number)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr giteaPR
+	if err := c.rest.getJSON(ctx, path, &pr); err != nil {
+		return nil, fmt.Errorf("failed to fetch Gitea PR: %w", err)
+	}
+
+	// Fetch the diff
+	diff, err := c.rest.getText(ctx, path+".diff")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Gitea PR diff: %w", err)
+	}
+
+	return &model.PullRequest{
+		Title:       pr.Title,
+		Branch:      pr.Head.Ref,
+		Description: pr.Body,
+		HeadSHA:     pr.Head.Sha,
+		Files:       parseUnifiedDiff(diff),
+	}, nil
+}
+
+func (c *Client) publishGiteaReview(ctx context.Context, repository, number string, review *model.Review) error {
+	path, err := giteaPRPath(repository, number)
+	if err != nil {
+		return err
+	}
+
+	// Unlike GitHub, Gitea anchors review comments by line number rather
+	// than diff position, but it still only accepts lines of the diff.
+	lines := mapDiffLines(review.PullRequest.Files)
+	var comments []map[string]interface{}
+	var unanchored []model.Feedback
+	for _, feedback := range review.CodeFeedback {
+		if feedback.Line == nil {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		if _, ok := lines[feedback.File][*feedback.Line]; !ok {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		comments = append(comments, map[string]interface{}{
+			"path":         feedback.File,
+			"new_position": *feedback.Line,
+			"body":         feedback.Suggestion,
+		})
+	}
+
+	err = c.rest.postJSON(ctx, path+"/reviews", map[string]interface{}{
+		"commit_id": review.PullRequest.HeadSHA,
+		"body":      reviewBody(review, unanchored),
+		"event":     "COMMENT",
+		"comments":  comments,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Gitea PR review: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestFetchGiteaPR(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token t" {
+			t.Errorf("%s: Authorization = %q, want %q", r.URL.Path, got, "token t")
+		}
+		switch r.URL.Path {
+		case "/git/api/v1/repos/o/r/pulls/4":
+			_, _ = w.Write([]byte(`{
+				"title": "Add main",
+				"body": "Adds the entry point.",
+				"head": {"ref": "feature", "sha": "abc123"}
+			}`))
+		case "/git/api/v1/repos/o/r/pulls/4.diff":
+			if accept := r.Header.Get("Accept"); accept == "application/json" {
+				t.Errorf("diff requested with Accept %q", accept)
+			}
+			_, _ = w.Write([]byte(testDiff))
+		case "/git/api/v1/repos/o/r/pulls/5":
+			http.Error(w, `{"message": "The target couldn't be found."}`, http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := New(&config.GitConfig{Provider: string(Gitea), Token: "t", BaseURL: srv.URL + "/git/"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pr, err := client.FetchPullRequest(context.Background(), "o/r", "4")
+	if err != nil {
+		t.Fatalf("FetchPullRequest() error = %v", err)
+	}
+
+	want := &model.PullRequest{
+		Title:       "Add main",
+		Branch:      "feature",
+		Description: "Adds the entry point.",
+		HeadSHA:     "abc123",
+		Files:       parseUnifiedDiff(testDiff),
+	}
+	if !reflect.DeepEqual(pr, want) {
+		t.Errorf("FetchPullRequest() =\n%+v\nwant\n%+v", pr, want)
+	}
+
+	if _, err := client.FetchPullRequest(context.Background(), "o/r", "5"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("FetchPullRequest() of a missing pull request error = %v, want a 404 error", err)
+	}
+}
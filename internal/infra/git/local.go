@@ -0,0 +1,151 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+type LocalMode string
+
+const (
+	// LocalBase reviews the commits of the current branch that are not on
+	// the base ref, like a pull request against it would.
+	LocalBase LocalMode = "base"
+	// LocalStaged reviews the changes in the index.
+	LocalStaged LocalMode = "staged"
+	// LocalUnstaged reviews the changes in the working tree that are not
+	// staged yet.
+	LocalUnstaged LocalMode = "unstaged"
+)
+
+// Local builds pull requests from a local repository by running git,
+// without talking to a hosting provider.
+type Local struct {
+	dir  string
+	base string
+	mode LocalMode
+}
+
+func NewLocal(dir, base string, mode LocalMode) (*Local, error) {
+	switch mode {
+	case LocalBase:
+		if base == "" {
+			return nil, fmt.Errorf("a base ref is required to review a local branch")
+		}
+	case LocalStaged, LocalUnstaged:
+	default:
+		return nil, fmt.Errorf("unsupported local mode: %s", mode)
+	}
+
+	l := &Local{
+		dir:  dir,
+		base: base,
+		mode: mode,
+	}
+
+	if _, err := l.git(context.Background(), "rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+
+	return l, nil
+}
+
+// FetchPullRequest ignores its arguments; the pull request is always built
+// from the repository and mode the Local was created with.
+func (l *Local) FetchPullRequest(ctx context.Context, _, _ string) (*model.PullRequest, error) {
+	branch, err := l.git(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve current branch: %w", err)
+	}
+	branch = strings.TrimSpace(branch)
+
+	diffArgs := []string{"diff", "--no-color", "--no-ext-diff"}
+	var title, description string
+	switch l.mode {
+	case LocalBase:
+		diffArgs = append(diffArgs, l.base+"...HEAD")
+		title, description, err = l.describeCommits(ctx)
+		if err != nil {
+			return nil, err
+		}
+	case LocalStaged:
+		diffArgs = append(diffArgs, "--cached")
+		title = fmt.Sprintf("Staged changes on %s", branch)
+	case LocalUnstaged:
+		title = fmt.Sprintf("Unstaged changes on %s", branch)
+	}
+
+	diff, err := l.git(ctx, diffArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff local changes: %w", err)
+	}
+
+	files := parseUnifiedDiff(diff)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no changes to review")
+	}
+
+	return &model.PullRequest{
+		Title:       title,
+		Branch:      branch,
+		Description: description,
+		Files:       files,
+	}, nil
+}
+
+// describeCommits derives a title and description from the commits between
+// the base ref and HEAD. A single commit gives its subject and body; with
+// several, the oldest subject is the title and all messages are listed.
+func (l *Local) describeCommits(ctx context.Context) (string, string, error) {
+	log, err := l.git(ctx, "log", "--reverse", "--format=%s%x00%b%x1e", l.base+"..HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read commit messages: %w", err)
+	}
+
+	type commit struct{ subject, body string }
+	var commits []commit
+	for _, entry := range strings.Split(log, "\x1e") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		subject, body, _ := strings.Cut(entry, "\x00")
+		commits = append(commits, commit{subject: subject, body: strings.TrimSpace(body)})
+	}
+
+	switch len(commits) {
+	case 0:
+		return "", "", fmt.Errorf("no commits between %s and HEAD", l.base)
+	case 1:
+		return commits[0].subject, commits[0].body, nil
+	}
+
+	var description strings.Builder
+	for _, c := range commits {
+		description.WriteString("- " + c.subject + "\n")
+		if c.body != "" {
+			description.WriteString("\n" + c.body + "\n\n")
+		}
+	}
+
+	return commits[0].subject, strings.TrimSpace(description.String()), nil
+}
+
+func (l *Local) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = l.dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,142 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+// mboxSeparator starts every mail in the output of "git format-patch".
+var mboxSeparator = regexp.MustCompile(`(?m)^From [0-9a-f]{40} `)
+
+// subjectPrefix matches the "[PATCH v2 1/3]" tag in front of a subject.
+var subjectPrefix = regexp.MustCompile(`^\[[^\]]*PATCH[^\]]*\]\s*`)
+
+// coverLetterSubject matches the subject of the "[PATCH 0/n]" mail that
+// introduces a series.
+var coverLetterSubject = regexp.MustCompile(`^\[[^\]]*PATCH[^\]]* 0+/\d+\]`)
+
+// Patch builds a pull request from a unified diff, either plain "git diff"
+// output or one or more mails produced by "git format-patch".
+type Patch struct {
+	name string
+	raw  string
+}
+
+// NewPatch reads the whole patch from r. The name is used as title when the
+// patch carries no mail headers.
+func NewPatch(name string, r io.Reader) (*Patch, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+
+	return &Patch{
+		name: name,
+		raw:  strings.ReplaceAll(string(raw), "\r\n", "\n"),
+	}, nil
+}
+
+// FetchPullRequest ignores its arguments; the pull request is always built
+// from the patch.
+func (p *Patch) FetchPullRequest(_ context.Context, _, _ string) (*model.PullRequest, error) {
+	files := parseUnifiedDiff(p.raw)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch %s", p.name)
+	}
+
+	title, description := p.describe()
+
+	return &model.PullRequest{
+		Title:       title,
+		Description: description,
+		Files:       files,
+	}, nil
+}
+
+type patchMail struct {
+	subject     string
+	body        string
+	coverLetter bool
+}
+
+// describe derives a title and description from the mail headers of the
+// patch. A cover letter ("[PATCH 0/n]") describes the whole series; without
+// one the messages are combined like for a local branch.
+func (p *Patch) describe() (string, string) {
+	var mails []patchMail
+	locs := mboxSeparator.FindAllStringIndex(p.raw, -1)
+	for i, loc := range locs {
+		end := len(p.raw)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		if mail, ok := parseMail(p.raw[loc[0]:end]); ok {
+			mails = append(mails, mail)
+		}
+	}
+
+	switch len(mails) {
+	case 0:
+		return fmt.Sprintf("Patch %s", p.name), ""
+	case 1:
+		return mails[0].subject, mails[0].body
+	}
+
+	if mails[0].coverLetter {
+		return mails[0].subject, mails[0].body
+	}
+
+	var description strings.Builder
+	for _, mail := range mails {
+		description.WriteString("- " + mail.subject + "\n")
+		if mail.body != "" {
+			description.WriteString("\n" + mail.body + "\n\n")
+		}
+	}
+
+	return mails[0].subject, strings.TrimSpace(description.String())
+}
+
+// parseMail reads the subject and the commit message body of a single
+// format-patch mail.
+func parseMail(mail string) (patchMail, bool) {
+	headers, body, _ := strings.Cut(mail, "\n\n")
+
+	var subject string
+	inSubject := false
+	for _, line := range strings.Split(headers, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Subject: "):
+			subject = strings.TrimPrefix(line, "Subject: ")
+			inSubject = true
+		case inSubject && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			// Long subjects are folded onto continuation lines.
+			subject += " " + strings.TrimSpace(line)
+		default:
+			inSubject = false
+		}
+	}
+	if subject == "" {
+		return patchMail{}, false
+	}
+
+	// The commit message ends at the "---" line in front of the diffstat.
+	var message []string
+	for _, line := range strings.Split(body, "\n") {
+		if line == "---" || strings.HasPrefix(line, "diff --git ") {
+			break
+		}
+		message = append(message, line)
+	}
+
+	return patchMail{
+		subject:     subjectPrefix.ReplaceAllString(subject, ""),
+		body:        strings.TrimSpace(strings.Join(message, "\n")),
+		coverLetter: coverLetterSubject.MatchString(subject),
+	}, true
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const patchDiff = `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-a
++b
+`
+
+func patchMailText(hash, subject, body string) string {
+	return "From " + strings.Repeat(hash, 40) + " Mon Sep 17 00:00:00 2001\n" +
+		"From: Jane Doe <jane@example.com>\n" +
+		"Date: Mon, 1 Jan 2024 00:00:00 +0000\n" +
+		"Subject: " + subject + "\n" +
+		"\n" +
+		body +
+		"---\n" +
+		" a.txt | 2 +-\n" +
+		"\n" +
+		patchDiff +
+		"--\n2.44.0\n\n"
+}
+
+func TestPatchDescribe(t *testing.T) {
+	tests := []struct {
+		name            string
+		raw             string
+		wantTitle       string
+		wantDescription string
+	}{
+		{
+			name:      "plain diff",
+			raw:       patchDiff,
+			wantTitle: "Patch fix.diff",
+		},
+		{
+			name:            "single mail",
+			raw:             patchMailText("a", "[PATCH] Fix the parser", "Lines were dropped.\n\nSigned-off-by: Jane Doe <jane@example.com>\n"),
+			wantTitle:       "Fix the parser",
+			wantDescription: "Lines were dropped.\n\nSigned-off-by: Jane Doe <jane@example.com>",
+		},
+		{
+			name:      "folded subject",
+			raw:       patchMailText("a", "[PATCH v2] Fix the parser\n for folded\n\tsubjects", ""),
+			wantTitle: "Fix the parser for folded subjects",
+		},
+		{
+			name: "series without cover letter",
+			raw: patchMailText("a", "[PATCH 1/2] Add the parser", "First part.\n") +
+				patchMailText("b", "[PATCH 2/2] Use the parser", ""),
+			wantTitle:       "Add the parser",
+			wantDescription: "- Add the parser\n\nFirst part.\n\n- Use the parser",
+		},
+		{
+			name: "series with cover letter",
+			raw: "From " + strings.Repeat("c", 40) + " Mon Sep 17 00:00:00 2001\n" +
+				"Subject: [PATCH 0/2] Parser rework\n\nRewrites the parser.\n\n" +
+				patchMailText("a", "[PATCH 1/2] Add the parser", "") +
+				patchMailText("b", "[PATCH 2/2] Use the parser", ""),
+			wantTitle:       "Parser rework",
+			wantDescription: "Rewrites the parser.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := NewPatch("fix.diff", strings.NewReader(tt.raw))
+			if err != nil {
+				t.Fatalf("NewPatch() error = %v", err)
+			}
+
+			title, description := patch.describe()
+			if title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", title, tt.wantTitle)
+			}
+			if description != tt.wantDescription {
+				t.Errorf("description = %q, want %q", description, tt.wantDescription)
+			}
+		})
+	}
+}
+
+func TestNewPatchCRLF(t *testing.T) {
+	patch, err := NewPatch("fix.diff", strings.NewReader(strings.ReplaceAll(patchMailText("a", "[PATCH] Fix", ""), "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("NewPatch() error = %v", err)
+	}
+
+	pr, err := patch.FetchPullRequest(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("FetchPullRequest() error = %v", err)
+	}
+	if pr.Title != "Fix" {
+		t.Errorf("Title = %q, want %q", pr.Title, "Fix")
+	}
+	if len(pr.Files) != 1 || len(pr.Files[0].Hunks) != 1 || len(pr.Files[0].Hunks[0].Lines) != 2 {
+		t.Errorf("Files = %+v, want one file with one hunk of two lines", pr.Files)
+	}
+}
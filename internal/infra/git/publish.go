@@ -0,0 +1,263 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+	"github.com/xanzy/go-gitlab"
+)
+
+func (c *Client) PublishReview(ctx context.Context, repository, number string, review *model.Review) error {
+	if review.PullRequest == nil {
+		return fmt.Errorf("the review does not reference the pull request it was made for")
+	}
+
+	switch c.provider {
+	case GitHub:
+		return c.publishGitHubReview(ctx, repository, number, review)
+	case GitLab:
+		return c.publishGitLabReview(ctx, repository, number, review)
+	case Bitbucket, BitbucketServer:
+		return c.publishBitbucketReview(ctx, repository, number, review)
+	case Gitea:
+		return c.publishGiteaReview(ctx, repository, number, review)
+	default:
+		return fmt.Errorf("publishing reviews is not supported for Git provider: %s", c.provider)
+	}
+}
+
+func (c *Client) publishGitHubReview(ctx context.Context, repository, number string, review *model.Review) error {
+	owner, repo, err := splitRepository(repository)
+	if err != nil {
+		return err
+	}
+
+	prNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return fmt.Errorf("invalid PR number: %w", err)
+	}
+
+	// Comments are anchored in the diff that was reviewed. Pinning the
+	// review to its commit makes GitHub translate or outdate them should
+	// the pull request have been pushed to since.
+	lines := mapDiffLines(review.PullRequest.Files)
+
+	// GitHub rejects the whole review when a single comment points outside
+	// the diff, so feedback that cannot be anchored goes into the body.
+	var comments []*github.DraftReviewComment
+	var unanchored []model.Feedback
+	for _, feedback := range review.CodeFeedback {
+		if feedback.Line == nil {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		line, ok := lines[feedback.File][*feedback.Line]
+		if !ok {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		comments = append(comments, &github.DraftReviewComment{
+			Path:     github.String(feedback.File),
+			Position: github.Int(line.position),
+			Body:     github.String(feedback.Suggestion),
+		})
+	}
+
+	_, _, err = c.githubClient.PullRequests.CreateReview(ctx, owner, repo, prNumber, &github.PullRequestReviewRequest{
+		CommitID: github.String(review.PullRequest.HeadSHA),
+		Body:     github.String(reviewBody(review, unanchored)),
+		Event:    github.String("COMMENT"),
+		Comments: comments,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub PR review: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) publishGitLabReview(ctx context.Context, project, number string, review *model.Review) error {
+	mrNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return fmt.Errorf("invalid MR number: %w", err)
+	}
+
+	// The positions refer to the diff that was reviewed, which GitLab
+	// keeps as a version of the merge request even after further pushes.
+	pr := review.PullRequest
+	files := pr.Files
+	lines := mapDiffLines(files)
+	oldPaths := make(map[string]string, len(files))
+	for _, file := range files {
+		oldPaths[file.Path()] = file.OldPath
+		if file.OldPath == "" {
+			oldPaths[file.Path()] = file.NewPath
+		}
+	}
+
+	var unanchored []model.Feedback
+	var discussions []*gitlab.CreateMergeRequestDiscussionOptions
+	for _, feedback := range review.CodeFeedback {
+		if feedback.Line == nil {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		line, ok := lines[feedback.File][*feedback.Line]
+		if !ok {
+			unanchored = append(unanchored, feedback)
+			continue
+		}
+
+		// GitLab identifies context lines by both sides and added lines by
+		// the new side only.
+		position := &gitlab.PositionOptions{
+			BaseSHA:      gitlab.Ptr(pr.BaseSHA),
+			StartSHA:     gitlab.Ptr(pr.StartSHA),
+			HeadSHA:      gitlab.Ptr(pr.HeadSHA),
+			PositionType: gitlab.Ptr("text"),
+			OldPath:      gitlab.Ptr(oldPaths[feedback.File]),
+			NewPath:      gitlab.Ptr(feedback.File),
+			NewLine:      gitlab.Ptr(*feedback.Line),
+		}
+		if line.oldLine > 0 {
+			position.OldLine = gitlab.Ptr(line.oldLine)
+		}
+
+		discussions = append(discussions, &gitlab.CreateMergeRequestDiscussionOptions{
+			Body:     gitlab.Ptr(feedback.Suggestion),
+			Position: position,
+		})
+	}
+
+	_, _, err = c.gitlabClient.Notes.CreateMergeRequestNote(project, mrNumber, &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.Ptr(reviewBody(review, unanchored)),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab MR note: %w", err)
+	}
+
+	for _, discussion := range discussions {
+		if _, _, err := c.gitlabClient.Discussions.CreateMergeRequestDiscussion(project, mrNumber, discussion, gitlab.WithContext(ctx)); err != nil {
+			return fmt.Errorf("failed to create GitLab MR discussion on %s: %w", *discussion.Position.NewPath, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) listGitLabMRDiffs(ctx context.Context, project string, number int) ([]*gitlab.MergeRequestDiff, error) {
+	opt := &gitlab.ListMergeRequestDiffsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var diffs []*gitlab.MergeRequestDiff
+	for {
+		page, resp, err := c.gitlabClient.MergeRequests.ListMergeRequestDiffs(project, number, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, page...)
+
+		if resp.NextPage == 0 {
+			return diffs, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// diffLine locates a new-side line of a file within the diff.
+type diffLine struct {
+	// position is what GitHub expects for review comments: the line after
+	// the first hunk header is position 1 and the count runs on through all
+	// following lines and hunk headers of the file.
+	position int
+	// oldLine is the old-side line number of an unchanged context line and
+	// zero for added lines.
+	oldLine int
+}
+
+// mapDiffLines maps the paths of files and their new-side line numbers to
+// their location in the diff. Lines outside the hunks are not part of the
+// map, since neither GitHub nor GitLab accept comments on them.
+func mapDiffLines(files []model.File) map[string]map[int]diffLine {
+	result := make(map[string]map[int]diffLine, len(files))
+	for _, file := range files {
+		lines := make(map[int]diffLine)
+		position := 0
+		for i, hunk := range file.Hunks {
+			if i > 0 {
+				position++
+			}
+			for _, line := range hunk.Lines {
+				position++
+				if line.NewLine > 0 {
+					lines[line.NewLine] = diffLine{position: position, oldLine: line.OldLine}
+				}
+			}
+		}
+		result[file.Path()] = lines
+	}
+
+	return result
+}
+
+// reviewBody renders the top-level comment of a published review.
+func reviewBody(review *model.Review, unanchored []model.Feedback) string {
+	var b strings.Builder
+	b.WriteString("## CodeCritique Review\n\n")
+	b.WriteString(review.Summary + "\n\n")
+	if review.OverallImpression != "" {
+		b.WriteString("**Overall impression:** " + review.OverallImpression + "\n\n")
+	}
+	if review.EstimatedEffort != "" {
+		b.WriteString("**Estimated effort to review:** " + review.EstimatedEffort + "\n\n")
+	}
+
+	writeList(&b, "Potential issues", review.PotentialIssues)
+	writeList(&b, "Suggestions", review.Suggestions)
+
+	if review.SecurityConcerns != "" {
+		b.WriteString("### Security concerns\n\n" + review.SecurityConcerns + "\n\n")
+	}
+	if review.Testing != "" {
+		b.WriteString("### Testing\n\n" + review.Testing + "\n\n")
+	}
+
+	if len(unanchored) > 0 {
+		b.WriteString("### Code feedback\n\n")
+		for _, feedback := range unanchored {
+			location := feedback.File
+			if feedback.Line != nil {
+				location = fmt.Sprintf("%s:%d", feedback.File, *feedback.Line)
+			}
+			b.WriteString(fmt.Sprintf("- `%s`: %s\n", location, feedback.Suggestion))
+		}
+		b.WriteString("\n")
+	}
+
+	// Readers of the pull request should know what the review did not cover.
+	writeList(&b, "Warnings", review.Metadata.Warnings)
+
+	return strings.TrimSpace(b.String())
+}
+
+func writeList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	b.WriteString("### " + title + "\n\n")
+	for _, item := range items {
+		b.WriteString("- " + item + "\n")
+	}
+	b.WriteString("\n")
+}
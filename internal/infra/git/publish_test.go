@@ -0,0 +1,233 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/config"
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestMapDiffLines(t *testing.T) {
+	files := parseUnifiedDiff(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++
++import "fmt"
+-import "os"
+@@ -10,2 +11,2 @@ func main() {
+-	os.Exit(1)
++	fmt.Println("bye")
+ }
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package gone
+`)
+
+	want := map[string]map[int]diffLine{
+		"main.go": {
+			1:  {position: 1, oldLine: 1},
+			2:  {position: 2},
+			3:  {position: 3},
+			11: {position: 7},
+			12: {position: 8, oldLine: 11},
+		},
+		"gone.go": {},
+	}
+
+	if got := mapDiffLines(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapDiffLines() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// TestPublishGitHubReview checks that the review is published against the
+// commit and the diff that were reviewed, without fetching the pull request
+// again.
+func TestPublishGitHubReview(t *testing.T) {
+	var got struct {
+		CommitID string `json:"commit_id"`
+		Body     string `json:"body"`
+		Event    string `json:"event"`
+		Comments []struct {
+			Path     string `json:"path"`
+			Position int    `json:"position"`
+			Body     string `json:"body"`
+		} `json:"comments"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/repos/o/r/pulls/7/reviews" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode review: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(&config.GitConfig{Provider: string(GitHub), Token: "t", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	review := &model.Review{
+		PullRequest: &model.PullRequest{
+			HeadSHA: "abc123",
+			Files: parseUnifiedDiff(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++
+ func main() {}
+`),
+		},
+		Summary: "Fine.",
+		CodeFeedback: []model.Feedback{
+			{File: "main.go", Line: intPtr(2), Suggestion: "Drop the blank line."},
+			{File: "main.go", Line: intPtr(40), Suggestion: "Outside the diff."},
+		},
+	}
+	if err := client.PublishReview(context.Background(), "o/r", "7", review); err != nil {
+		t.Fatalf("PublishReview() error = %v", err)
+	}
+
+	if got.CommitID != "abc123" || got.Event != "COMMENT" {
+		t.Errorf("commit_id = %q, event = %q, want abc123 and COMMENT", got.CommitID, got.Event)
+	}
+	if len(got.Comments) != 1 || got.Comments[0].Path != "main.go" || got.Comments[0].Position != 2 {
+		t.Errorf("comments = %+v, want one on main.go at position 2", got.Comments)
+	}
+	if !strings.Contains(got.Body, "`main.go:40`: Outside the diff.") {
+		t.Errorf("body = %q, want the unanchored feedback", got.Body)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// TestPublishGitLabReview checks that discussions are positioned with the
+// diff refs recorded when the merge request was fetched.
+func TestPublishGitLabReview(t *testing.T) {
+	type position struct {
+		BaseSHA      string `json:"base_sha"`
+		StartSHA     string `json:"start_sha"`
+		HeadSHA      string `json:"head_sha"`
+		PositionType string `json:"position_type"`
+		OldPath      string `json:"old_path"`
+		NewPath      string `json:"new_path"`
+		OldLine      int    `json:"old_line"`
+		NewLine      int    `json:"new_line"`
+	}
+	var note string
+	var discussions []position
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Body     string    `json:"body"`
+			Position *position `json:"position"`
+		}
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		switch r.URL.Path {
+		case "/api/v4/projects/g/p/merge_requests/3/notes":
+			note = body.Body
+			_, _ = w.Write([]byte(`{"id": 1}`))
+		case "/api/v4/projects/g/p/merge_requests/3/discussions":
+			discussions = append(discussions, *body.Position)
+			_, _ = w.Write([]byte(`{"id": "d1"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := New(&config.GitConfig{Provider: string(GitLab), Token: "t", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	review := &model.Review{
+		PullRequest: &model.PullRequest{
+			BaseSHA:  "base",
+			StartSHA: "start",
+			HeadSHA:  "head",
+			Files: parseUnifiedDiff(`diff --git a/old.go b/new.go
+similarity index 90%
+rename from old.go
+rename to new.go
+--- a/old.go
++++ b/new.go
+@@ -1,2 +1,3 @@
+ package main
++
+ func main() {}
+`),
+		},
+		Summary: "Fine.",
+		CodeFeedback: []model.Feedback{
+			{File: "new.go", Line: intPtr(2), Suggestion: "Drop the blank line."},
+			{File: "new.go", Line: intPtr(3), Suggestion: "Add a doc comment."},
+			{File: "new.go", Suggestion: "Consider keeping the old name."},
+		},
+	}
+	if err := client.PublishReview(context.Background(), "g/p", "3", review); err != nil {
+		t.Fatalf("PublishReview() error = %v", err)
+	}
+
+	want := []position{
+		{BaseSHA: "base", StartSHA: "start", HeadSHA: "head", PositionType: "text", OldPath: "old.go", NewPath: "new.go", NewLine: 2},
+		{BaseSHA: "base", StartSHA: "start", HeadSHA: "head", PositionType: "text", OldPath: "old.go", NewPath: "new.go", OldLine: 2, NewLine: 3},
+	}
+	if !reflect.DeepEqual(discussions, want) {
+		t.Errorf("discussions =\n%+v\nwant\n%+v", discussions, want)
+	}
+	if !strings.Contains(note, "`new.go`: Consider keeping the old name.") {
+		t.Errorf("note = %q, want the unanchored feedback", note)
+	}
+}
+
+func TestReviewBody(t *testing.T) {
+	review := &model.Review{
+		Summary:           "Adds the entry point.",
+		OverallImpression: "Fine.",
+		Suggestions:       []string{"Add a test."},
+		Metadata: model.Metadata{
+			Warnings: []string{"GitHub returned no patch for logo.png because it is binary or too large; it was not reviewed"},
+		},
+	}
+	unanchored := []model.Feedback{{File: "main.go", Suggestion: "Document the package."}}
+
+	want := "## CodeCritique Review\n\n" +
+		"Adds the entry point.\n\n" +
+		"**Overall impression:** Fine.\n\n" +
+		"### Suggestions\n\n" +
+		"- Add a test.\n\n" +
+		"### Code feedback\n\n" +
+		"- `main.go`: Document the package.\n\n" +
+		"### Warnings\n\n" +
+		"- GitHub returned no patch for logo.png because it is binary or too large; it was not reviewed"
+	if got := reviewBody(review, unanchored); got != want {
+		t.Errorf("reviewBody() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/holistic-engineering/codecritique/config"
+)
+
+// newHTTPClient returns the HTTP client used to talk to the Git provider,
+// trusting the configured CA bundle in addition to the system roots.
+func newHTTPClient(cfg *config.GitConfig) (*http.Client, error) {
+	if cfg.CAFile == "" && !cfg.InsecureSkipVerify {
+		return &http.Client{}, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // #nosec G402 -- explicitly requested in the configuration
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// restClient talks to the REST APIs of providers that have no Go SDK among
+// our dependencies.
+type restClient struct {
+	httpClient *http.Client
+	baseURL    string
+	authorize  func(*http.Request)
+}
+
+func (r *restClient) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := r.do(ctx, http.MethodGet, path, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// getText reads a plain text resource such as a diff. Some servers answer
+// requests that only accept JSON with an error or a JSON wrapper instead.
+func (r *restClient) getText(ctx context.Context, path string) (string, error) {
+	resp, err := r.do(ctx, http.MethodGet, path, "text/plain, */*", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response of %s: %w", path, err)
+	}
+
+	return string(body), nil
+}
+
+func (r *restClient) postJSON(ctx context.Context, path string, body interface{}) error {
+	resp, err := r.do(ctx, http.MethodPost, path, "application/json", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (r *restClient) do(ctx context.Context, method, path, accept string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		requestBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(requestBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.authorize(req)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", req.URL.Host, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s %s returned non-OK status: %s, body: %s", method, path, resp.Status, string(bodyBytes))
+	}
+
+	return resp, nil
+}
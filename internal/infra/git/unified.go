@@ -0,0 +1,220 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+// contextLines is the number of unchanged lines kept around every change,
+// as in "git diff".
+const contextLines = 3
+
+// diffHunks compares two versions of a file line by line and returns the
+// hunks of their unified diff. It is used for providers whose APIs only
+// return file contents.
+func diffHunks(oldText, newText string) []model.Hunk {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+
+	var hunks []model.Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == model.LineContext {
+			i++
+			continue
+		}
+
+		// Extend the hunk over the following changes until a run of
+		// unchanged lines long enough to separate two hunks.
+		start := max(i-contextLines, 0)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Kind == model.LineContext {
+				continue
+			}
+			if j-end > 2*contextLines {
+				break
+			}
+			end = j
+		}
+		end = min(end+contextLines+1, len(lines))
+
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+
+	return hunks
+}
+
+func newHunk(lines []model.Line) model.Hunk {
+	hunk := model.Hunk{Lines: lines}
+	for _, line := range lines {
+		if line.Kind != model.LineAdded {
+			if hunk.OldStart == 0 {
+				hunk.OldStart = line.OldLine
+			}
+			hunk.OldLines++
+		}
+		if line.Kind != model.LineDeleted {
+			if hunk.NewStart == 0 {
+				hunk.NewStart = line.NewLine
+			}
+			hunk.NewLines++
+		}
+	}
+
+	// Like git, an empty side of a hunk starts at the line before it.
+	if hunk.OldLines == 0 {
+		hunk.OldStart = lines[0].NewLine - 1
+	}
+	if hunk.NewLines == 0 {
+		hunk.NewStart = lines[0].OldLine - 1
+	}
+
+	return hunk
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a shortest edit script between a and b with the
+// linear space variant of Myers' algorithm and returns it as numbered diff
+// lines.
+func diffLines(a, b []string) []model.Line {
+	d := &differ{a: a, b: b}
+	d.diff(0, len(a), 0, len(b))
+	return d.lines
+}
+
+// differ splits a and b at the middle snake of their edit graph and recurses
+// into both halves, so only two vectors of the size of the inputs are kept
+// instead of one per edit.
+type differ struct {
+	a, b  []string
+	lines []model.Line
+}
+
+// diff appends the edit script between a[aLo:aHi] and b[bLo:bHi].
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	prefix := 0
+	for aLo+prefix < aHi && bLo+prefix < bHi && d.a[aLo+prefix] == d.b[bLo+prefix] {
+		prefix++
+	}
+	suffix := 0
+	for aHi-suffix > aLo+prefix && bHi-suffix > bLo+prefix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+
+	d.context(aLo, bLo, prefix)
+	aLo, bLo = aLo+prefix, bLo+prefix
+	aEnd, bEnd := aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aEnd:
+		for y := bLo; y < bEnd; y++ {
+			d.lines = append(d.lines, model.Line{Kind: model.LineAdded, Content: d.b[y], NewLine: y + 1})
+		}
+	case bLo == bEnd:
+		for x := aLo; x < aEnd; x++ {
+			d.lines = append(d.lines, model.Line{Kind: model.LineDeleted, Content: d.a[x], OldLine: x + 1})
+		}
+	default:
+		x, y := d.bisect(aLo, aEnd, bLo, bEnd)
+		d.diff(aLo, x, bLo, y)
+		d.diff(x, aEnd, y, bEnd)
+	}
+
+	d.context(aEnd, bEnd, suffix)
+}
+
+func (d *differ) context(x, y, n int) {
+	for i := 0; i < n; i++ {
+		d.lines = append(d.lines, model.Line{Kind: model.LineContext, Content: d.a[x+i], OldLine: x + i + 1, NewLine: y + i + 1})
+	}
+}
+
+// bisect searches forward from the start and backward from the end of
+// a[aLo:aHi] and b[bLo:bHi] at the same time, and returns the point where
+// the two paths meet. Both ranges must not be empty.
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (int, int) {
+	a, b := d.a[aLo:aHi], d.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	// With an odd delta the paths meet during a forward step, otherwise
+	// during a backward one.
+	delta := n - m
+	odd := delta%2 != 0
+
+	// Diagonals that ran off the edit graph are not searched any further.
+	var fStart, fEnd, bStart, bEnd int
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				i := offset + delta - k
+				if i >= 0 && i < len(backward) && backward[i] != -1 && x >= n-backward[i] {
+					return aLo + x, bLo + y
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				i := offset + delta - k
+				if i >= 0 && i < len(forward) && forward[i] != -1 && forward[i] >= n-x {
+					fx := forward[i]
+					return aLo + fx, bLo + fx - (i - offset)
+				}
+			}
+		}
+	}
+
+	// Nothing in common: every line of a is replaced.
+	return aHi, bLo
+}
@@ -0,0 +1,172 @@
+package git
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/holistic-engineering/codecritique/internal/critique/model"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []model.Line
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: []model.Line{
+				{Kind: model.LineContext, Content: "a", OldLine: 1, NewLine: 1},
+				{Kind: model.LineContext, Content: "b", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name: "added file",
+			b:    "a\nb\n",
+			want: []model.Line{
+				{Kind: model.LineAdded, Content: "a", NewLine: 1},
+				{Kind: model.LineAdded, Content: "b", NewLine: 2},
+			},
+		},
+		{
+			name: "deleted file",
+			a:    "a\n",
+			want: []model.Line{
+				{Kind: model.LineDeleted, Content: "a", OldLine: 1},
+			},
+		},
+		{
+			name: "replaced line",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			want: []model.Line{
+				{Kind: model.LineContext, Content: "a", OldLine: 1, NewLine: 1},
+				{Kind: model.LineDeleted, Content: "b", OldLine: 2},
+				{Kind: model.LineAdded, Content: "x", NewLine: 2},
+				{Kind: model.LineContext, Content: "c", OldLine: 3, NewLine: 3},
+			},
+		},
+		{
+			name: "nothing in common",
+			a:    "a\nb\n",
+			b:    "c\n",
+			want: []model.Line{
+				{Kind: model.LineDeleted, Content: "a", OldLine: 1},
+				{Kind: model.LineDeleted, Content: "b", OldLine: 2},
+				{Kind: model.LineAdded, Content: "c", NewLine: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffLines(splitLines(tt.a), splitLines(tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesShortest checks on random inputs that the edit script turns
+// a into b and is as short as the longest common subsequence allows.
+func TestDiffLinesShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(40))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		lines := diffLines(a, b)
+
+		var oldLines, newLines []string
+		edits := 0
+		for _, line := range lines {
+			if line.Kind != model.LineAdded {
+				oldLines = append(oldLines, line.Content)
+				if line.OldLine != len(oldLines) {
+					t.Fatalf("diffLines(%q, %q): old line %d numbered %d", a, b, len(oldLines), line.OldLine)
+				}
+			}
+			if line.Kind != model.LineDeleted {
+				newLines = append(newLines, line.Content)
+				if line.NewLine != len(newLines) {
+					t.Fatalf("diffLines(%q, %q): new line %d numbered %d", a, b, len(newLines), line.NewLine)
+				}
+			}
+			if line.Kind != model.LineContext {
+				edits++
+			}
+		}
+
+		if !slices.Equal(oldLines, a) || !slices.Equal(newLines, b) {
+			t.Fatalf("diffLines(%q, %q) does not reproduce both sides: %+v", a, b, lines)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("diffLines(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffHunks(t *testing.T) {
+	var oldLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, string(rune('a'+i-1)))
+	}
+	newLines := append([]string(nil), oldLines...)
+	newLines[1] = "B"  // line 2
+	newLines[15] = "P" // line 16, too far away to share a hunk
+
+	hunks := diffHunks(strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n")
+	if len(hunks) != 2 {
+		t.Fatalf("diffHunks() returned %d hunks, want 2: %+v", len(hunks), hunks)
+	}
+
+	want := []struct{ oldStart, oldLines, newStart, newLines int }{
+		{1, 5, 1, 5},
+		{13, 7, 13, 7},
+	}
+	for i, hunk := range hunks {
+		got := struct{ oldStart, oldLines, newStart, newLines int }{hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines}
+		if got != want[i] {
+			t.Errorf("hunk %d = -%d,%d +%d,%d, want -%d,%d +%d,%d", i, got.oldStart, got.oldLines, got.newStart, got.newLines,
+				want[i].oldStart, want[i].oldLines, want[i].newStart, want[i].newLines)
+		}
+	}
+}
+
+func TestDiffHunksAddedFile(t *testing.T) {
+	hunks := diffHunks("", "a\nb\n")
+	if len(hunks) != 1 {
+		t.Fatalf("diffHunks() returned %d hunks, want 1", len(hunks))
+	}
+	if hunk := hunks[0]; hunk.OldStart != 0 || hunk.OldLines != 0 || hunk.NewStart != 1 || hunk.NewLines != 2 {
+		t.Errorf("hunk = -%d,%d +%d,%d, want -0,0 +1,2", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	}
+}
@@ -0,0 +1,161 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Target is a pull request identified by its web URL.
+type Target struct {
+	Provider Provider
+	// BaseURL and UploadURL are the API URLs of a self-hosted instance and
+	// empty for the public services.
+	BaseURL   string
+	UploadURL string
+	// Repository is the full path of the repository, in the form that
+	// Client.FetchPullRequest expects for the provider.
+	Repository string
+	Number     string
+}
+
+// publicHosts are the hosts of the public services, which are used when no
+// base URL is configured.
+var publicHosts = map[Provider]string{
+	GitHub:      "github.com",
+	GitLab:      "gitlab.com",
+	Bitbucket:   "bitbucket.org",
+	AzureDevOps: "dev.azure.com",
+}
+
+// Host returns the host that a client for the provider and base URL talks
+// to, with the API hosts of the public services folded into their web
+// hosts. It is empty when the provider has no public service and no base URL
+// is set.
+func Host(provider Provider, baseURL string) string {
+	if baseURL == "" {
+		return publicHosts[provider]
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch host {
+	case "api.github.com":
+		return "github.com"
+	case "api.bitbucket.org":
+		return "bitbucket.org"
+	}
+	return host
+}
+
+// Host returns the host of the pull request, as Host does for a
+// configuration.
+func (t *Target) Host() string {
+	return Host(t.Provider, t.BaseURL)
+}
+
+// IsURL reports whether a command line argument is a web URL rather than an
+// owner/repo path.
+func IsURL(arg string) bool {
+	return strings.HasPrefix(arg, "https://") || strings.HasPrefix(arg, "http://")
+}
+
+// ParseURL infers the provider, host, repository and number of a pull
+// request from the URL of its web page, such as
+// https://github.com/o/r/pull/12 or
+// https://gitlab.example.com/g/s/p/-/merge_requests/7.
+func ParseURL(raw string) (*Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull request URL: %w", err)
+	}
+
+	host := u.Hostname()
+	origin := u.Scheme + "://" + u.Host
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	invalid := fmt.Errorf("unrecognized pull request URL: %s", raw)
+
+	// GitLab: /<namespace...>/<project>/-/merge_requests/<n>
+	if i := slices.Index(segments, "-"); i >= 2 && len(segments) > i+2 && segments[i+1] == "merge_requests" {
+		target := &Target{Provider: GitLab, Repository: strings.Join(segments[:i], "/"), Number: segments[i+2]}
+		if host != "gitlab.com" {
+			target.BaseURL = origin
+		}
+		return target.validate(invalid)
+	}
+
+	// Azure DevOps: /[<prefix>/]<organization>/<project>/_git/<repo>/pullrequest/<n>
+	if i := slices.Index(segments, "_git"); i >= 1 && len(segments) > i+3 && segments[i+2] == "pullrequest" {
+		target := &Target{Provider: AzureDevOps, Number: segments[i+3]}
+		switch {
+		case strings.HasSuffix(host, ".visualstudio.com") && i == 1:
+			organization := strings.TrimSuffix(host, ".visualstudio.com")
+			target.Repository = organization + "/" + segments[0] + "/" + segments[i+1]
+		case i >= 2:
+			target.Repository = strings.Join(segments[i-2:i], "/") + "/" + segments[i+1]
+			if host != "dev.azure.com" {
+				target.BaseURL = origin + "/" + strings.Join(segments[:i-2], "/")
+				target.BaseURL = strings.TrimSuffix(target.BaseURL, "/")
+			}
+		default:
+			return nil, invalid
+		}
+		return target.validate(invalid)
+	}
+
+	// Bitbucket Server: /[<prefix>/]projects/<key>/repos/<repo>/pull-requests/<n>
+	if i := slices.Index(segments, "projects"); i >= 0 && len(segments) > i+5 && segments[i+2] == "repos" && segments[i+4] == "pull-requests" {
+		target := &Target{
+			Provider:   BitbucketServer,
+			BaseURL:    strings.TrimSuffix(origin+"/"+strings.Join(segments[:i], "/"), "/"),
+			Repository: segments[i+1] + "/" + segments[i+3],
+			Number:     segments[i+5],
+		}
+		return target.validate(invalid)
+	}
+
+	if len(segments) < 4 {
+		return nil, invalid
+	}
+	target := &Target{Repository: segments[0] + "/" + segments[1], Number: segments[3]}
+	switch segments[2] {
+	case "pull":
+		// GitHub and GitHub Enterprise Server
+		target.Provider = GitHub
+		if host != "github.com" {
+			target.BaseURL = origin + "/api/v3/"
+			target.UploadURL = origin + "/api/uploads/"
+		}
+	case "pulls":
+		// Gitea and Forgejo
+		target.Provider = Gitea
+		target.BaseURL = origin
+	case "pull-requests":
+		// Bitbucket Cloud
+		if host != "bitbucket.org" {
+			return nil, invalid
+		}
+		target.Provider = Bitbucket
+	default:
+		return nil, invalid
+	}
+
+	return target.validate(invalid)
+}
+
+func (t *Target) validate(invalid error) (*Target, error) {
+	if t.Repository == "" {
+		return nil, invalid
+	}
+	if _, err := strconv.Atoi(t.Number); err != nil {
+		return nil, invalid
+	}
+
+	return t, nil
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    *Target
+		wantErr bool
+	}{
+		{
+			url:  "https://github.com/o/r/pull/12",
+			want: &Target{Provider: GitHub, Repository: "o/r", Number: "12"},
+		},
+		{
+			url:  "https://github.com/o/r/pull/12/files#diff-1",
+			want: &Target{Provider: GitHub, Repository: "o/r", Number: "12"},
+		},
+		{
+			url: "https://github.example.com/o/r/pull/3",
+			want: &Target{
+				Provider:   GitHub,
+				BaseURL:    "https://github.example.com/api/v3/",
+				UploadURL:  "https://github.example.com/api/uploads/",
+				Repository: "o/r",
+				Number:     "3",
+			},
+		},
+		{
+			url:  "https://gitlab.com/g/s/p/-/merge_requests/7",
+			want: &Target{Provider: GitLab, Repository: "g/s/p", Number: "7"},
+		},
+		{
+			url:  "https://gitlab.example.com/g/p/-/merge_requests/7/diffs",
+			want: &Target{Provider: GitLab, BaseURL: "https://gitlab.example.com", Repository: "g/p", Number: "7"},
+		},
+		{
+			url:  "https://bitbucket.org/w/r/pull-requests/5",
+			want: &Target{Provider: Bitbucket, Repository: "w/r", Number: "5"},
+		},
+		{
+			url:  "https://bitbucket.example.com/projects/KEY/repos/r/pull-requests/9/overview",
+			want: &Target{Provider: BitbucketServer, BaseURL: "https://bitbucket.example.com", Repository: "KEY/r", Number: "9"},
+		},
+		{
+			url:  "https://git.example.com/bitbucket/projects/KEY/repos/r/pull-requests/9",
+			want: &Target{Provider: BitbucketServer, BaseURL: "https://git.example.com/bitbucket", Repository: "KEY/r", Number: "9"},
+		},
+		{
+			url:  "https://gitea.example.com/o/r/pulls/4",
+			want: &Target{Provider: Gitea, BaseURL: "https://gitea.example.com", Repository: "o/r", Number: "4"},
+		},
+		{
+			url:  "https://dev.azure.com/org/proj/_git/repo/pullrequest/17",
+			want: &Target{Provider: AzureDevOps, Repository: "org/proj/repo", Number: "17"},
+		},
+		{
+			url:  "https://org.visualstudio.com/proj/_git/repo/pullrequest/17",
+			want: &Target{Provider: AzureDevOps, Repository: "org/proj/repo", Number: "17"},
+		},
+		{
+			url:  "https://tfs.example.com/tfs/coll/proj/_git/repo/pullrequest/17",
+			want: &Target{Provider: AzureDevOps, BaseURL: "https://tfs.example.com/tfs", Repository: "coll/proj/repo", Number: "17"},
+		},
+		{url: "https://github.com/o/r", wantErr: true},
+		{url: "https://github.com/o/r/pull/latest", wantErr: true},
+		{url: "https://github.com/o/r/issues/12", wantErr: true},
+		{url: "https://bitbucket.example.com/w/r/pull-requests/5", wantErr: true},
+		{url: "https://dev.azure.com/proj/_git/repo/pullrequest/17", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseURL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		baseURL  string
+		want     string
+	}{
+		{GitHub, "", "github.com"},
+		{GitHub, "https://api.github.com/", "github.com"},
+		{GitHub, "https://GitHub.example.com/api/v3/", "github.example.com"},
+		{GitLab, "", "gitlab.com"},
+		{Bitbucket, "https://api.bitbucket.org/2.0", "bitbucket.org"},
+		{AzureDevOps, "", "dev.azure.com"},
+		{Gitea, "", ""},
+		{Gitea, "https://gitea.example.com:3000", "gitea.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := Host(tt.provider, tt.baseURL); got != tt.want {
+			t.Errorf("Host(%s, %q) = %q, want %q", tt.provider, tt.baseURL, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io"
 
 	"github.com/holistic-engineering/codecritique/internal/critique/model"
 )
@@ -14,7 +15,7 @@ func (p *htmlPrinter) Kind() Kind {
 	return KindHTML
 }
 
-func (p *htmlPrinter) Print(review *model.Review) error {
+func (p *htmlPrinter) Print(w io.Writer, review *model.Review) error {
 	tmpl, err := template.New("review").Parse(htmlTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse HTML template: %w", err)
@@ -25,7 +26,9 @@ func (p *htmlPrinter) Print(review *model.Review) error {
 		return fmt.Errorf("failed to execute HTML template: %w", err)
 	}
 
-	fmt.Print(buf.String())
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write review: %w", err)
+	}
 	return nil
 }
 
@@ -114,6 +117,17 @@ const htmlTemplate = `
             </div>
             {{end}}
         </div>
+
+        {{if .Metadata.Warnings}}
+        <div class="bg-yellow-50 shadow-md rounded-lg p-6 mt-6">
+            <h2 class="text-2xl font-semibold mb-4">Warnings</h2>
+            <ul class="list-disc pl-6">
+                {{range .Metadata.Warnings}}
+                <li>{{.}}</li>
+                {{end}}
+            </ul>
+        </div>
+        {{end}}
     </div>
 </body>
 </html>
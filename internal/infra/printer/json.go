@@ -3,6 +3,7 @@ package printer
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/holistic-engineering/codecritique/internal/critique/model"
 )
@@ -13,12 +14,14 @@ func (p *jsonPrinter) Kind() Kind {
 	return KindJSON
 }
 
-func (p *jsonPrinter) Print(review *model.Review) error {
+func (p *jsonPrinter) Print(w io.Writer, review *model.Review) error {
 	reviewJSON, err := json.MarshalIndent(review, "", "    ")
 	if err != nil {
 		return fmt.Errorf("json.MarshalIndent: %w", err)
 	}
 
-	fmt.Print(string(reviewJSON))
+	if _, err := w.Write(reviewJSON); err != nil {
+		return fmt.Errorf("failed to write review: %w", err)
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package printer
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"text/template"
 
 	"github.com/holistic-engineering/codecritique/internal/critique/model"
@@ -14,7 +15,7 @@ func (p *markdownPrinter) Kind() Kind {
 	return KindMarkdown
 }
 
-func (p *markdownPrinter) Print(review *model.Review) error {
+func (p *markdownPrinter) Print(w io.Writer, review *model.Review) error {
 	tmpl, err := template.New("review").Parse(markdownTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse Markdown template: %w", err)
@@ -25,7 +26,9 @@ func (p *markdownPrinter) Print(review *model.Review) error {
 		return fmt.Errorf("failed to execute Markdown template: %w", err)
 	}
 
-	fmt.Print(buf.String())
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write review: %w", err)
+	}
 	return nil
 }
 
@@ -85,5 +88,12 @@ const markdownTemplate = `
 {{if .Line}}**Line:** {{.Line}}{{end}}
 **Suggestion:** {{.Suggestion}}
 
+{{end}}
+{{if .Metadata.Warnings}}
+## Warnings
+
+{{range .Metadata.Warnings}}
+- {{.}}
+{{end}}
 {{end}}
 `
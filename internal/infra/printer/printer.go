@@ -2,6 +2,8 @@ package printer
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/holistic-engineering/codecritique/config"
 	"github.com/holistic-engineering/codecritique/internal/critique/model"
@@ -16,12 +18,13 @@ const (
 )
 
 type printer interface {
-	Print(*model.Review) error
+	Print(io.Writer, *model.Review) error
 	Kind() Kind
 }
 
 type Printer struct {
 	printer printer
+	out     io.Writer
 }
 
 func New(cfg *config.PrinterConfig) (*Printer, error) {
@@ -29,22 +32,35 @@ func New(cfg *config.PrinterConfig) (*Printer, error) {
 	case KindJSON:
 		return &Printer{
 			printer: &jsonPrinter{},
+			out:     os.Stdout,
 		}, nil
 	case KindHTML:
 		return &Printer{
 			printer: &htmlPrinter{},
+			out:     os.Stdout,
 		}, nil
 	case KindMarkdown:
 		return &Printer{
 			printer: &markdownPrinter{},
+			out:     os.Stdout,
 		}, nil
 	default:
 		return nil, fmt.Errorf("printer kind %s not available", cfg.Kind)
 	}
 }
 
+// WithOutput makes Print write to w instead of stdout.
+func (p *Printer) WithOutput(w io.Writer) *Printer {
+	p.out = w
+	return p
+}
+
+func (p *Printer) Kind() Kind {
+	return p.printer.Kind()
+}
+
 func (p *Printer) Print(review *model.Review) error {
-	if err := p.printer.Print(review); err != nil {
+	if err := p.printer.Print(p.out, review); err != nil {
 		return fmt.Errorf("could not print for kind %s: %w", p.printer.Kind(), err)
 	}
 	return nil